@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	sink := fs.String("log-sink", "stderr", "")
+	level := fs.String("log-level", "info", "")
+	limit := fs.Int("search-limit-st", 20, "")
+	_ = fs.Parse([]string{"-log-level", "debug"})
+
+	err := applyConfig(fs, strings.NewReader(`
+# comment
+log-sink = "syslog"
+log-level = warn
+search-limit-st=5
+`), "test.conf")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "syslog", *sink)
+	assert.Equal(t, "debug", *level)
+	assert.Equal(t, 5, *limit)
+}
+
+func TestApplyConfig_UnknownSetting(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+
+	err := applyConfig(fs, strings.NewReader("log-colour = blue\n"), "test.conf")
+
+	assert.EqualError(t, err, `test.conf:1: unknown setting: "log-colour"`)
+}
+
+func TestApplyConfig_InvalidValue(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("search-limit-st", 20, "")
+
+	err := applyConfig(fs, strings.NewReader("\nsearch-limit-st = lots\n"), "test.conf")
+
+	assert.ErrorContains(t, err, "test.conf:2: invalid value for search-limit-st")
+}
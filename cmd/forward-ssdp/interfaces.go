@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/edutko/go-forward-ssdp/internal/netutil"
+)
+
+type interfaceReport struct {
+	netutil.InterfaceInfo
+	Selected bool     `json:"selected"`
+	Reasons  []string `json:"reasons,omitempty"`
+}
+
+// interfacesCommand lists every interface and whether the relay would use it: whether it is one of
+// the named interfaces or matches -interface-query, or otherwise according to the default query.
+func interfacesCommand(args []string) error {
+	fs := flag.NewFlagSet("interfaces", flag.ExitOnError)
+	queryString := fs.String("interface-query", "", "query to preview, as for the relay")
+	asJSON := fs.Bool("json", false, "print interfaces as JSON")
+	_ = fs.Parse(args)
+
+	query := defaultInterfaceQuery()
+	switch {
+	case fs.NArg() > 0 && *queryString != "":
+		return errors.New("interface names and a query cannot both be given")
+	case fs.NArg() > 0:
+		query = []netutil.QueryParam{netutil.WithNames(fs.Args()...)}
+	case *queryString != "":
+		q, err := netutil.ParseQuery(*queryString)
+		if err != nil {
+			return err
+		}
+		query = []netutil.QueryParam{q}
+	}
+
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return fmt.Errorf("listing interfaces: %w", err)
+	}
+	return writeInterfaces(os.Stdout, ifs, query, *asJSON)
+}
+
+func writeInterfaces(w io.Writer, ifs []net.Interface, query []netutil.QueryParam, asJSON bool) error {
+	var reports []interfaceReport
+	for _, ifi := range ifs {
+		selected, reasons, err := netutil.Explain(ifi, query...)
+		if err != nil {
+			return err
+		}
+
+		if !asJSON {
+			_, _ = io.WriteString(w, netutil.InterfaceToString(ifi))
+			if selected {
+				_, _ = io.WriteString(w, "  Selected: yes\n")
+			} else {
+				_, _ = fmt.Fprintf(w, "  Selected: no (%s)\n", strings.Join(reasons, "; "))
+			}
+			continue
+		}
+
+		reports = append(reports, interfaceReport{
+			InterfaceInfo: netutil.Describe(ifi),
+			Selected:      selected,
+			Reasons:       reasons,
+		})
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	return nil
+}
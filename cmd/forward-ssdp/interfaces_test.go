@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edutko/go-forward-ssdp/internal/netutil"
+)
+
+func TestWriteInterfaces(t *testing.T) {
+	ifs := []net.Interface{{
+		Index:        999999,
+		Name:         "lo0",
+		HardwareAddr: net.HardwareAddr{0x00, 0x01, 0x02, 0x03, 0x04, 0x05},
+		Flags:        net.FlagUp | net.FlagLoopback | net.FlagMulticast,
+	}}
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeInterfaces(&buf, ifs, defaultInterfaceQuery(), false))
+	assert.Contains(t, buf.String(), "lo0 (00:01:02:03:04:05)\n  Flags: up, loopback, multicast\n")
+	assert.Contains(t, buf.String(), "  Selected: no (is loopback; has no IPv4 address)\n")
+
+	buf.Reset()
+	assert.Nil(t, writeInterfaces(&buf, ifs, []netutil.QueryParam{netutil.WithNames("lo0")}, true))
+	var reports []interfaceReport
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &reports))
+	assert.Len(t, reports, 1)
+	assert.Equal(t, "lo0", reports[0].Name)
+	assert.Equal(t, []string{"up", "loopback", "multicast"}, reports[0].Flags)
+	assert.NotNil(t, reports[0].Unicast)
+	assert.True(t, reports[0].Selected)
+	assert.Empty(t, reports[0].Reasons)
+}
@@ -1,46 +1,393 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/edutko/go-forward-ssdp/internal/admin"
+	"github.com/edutko/go-forward-ssdp/internal/logging"
+	"github.com/edutko/go-forward-ssdp/internal/mdns"
 	"github.com/edutko/go-forward-ssdp/internal/netutil"
+	"github.com/edutko/go-forward-ssdp/internal/privdrop"
 	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+	"github.com/edutko/go-forward-ssdp/internal/systemd"
+	"github.com/edutko/go-forward-ssdp/internal/wol"
+	"github.com/edutko/go-forward-ssdp/internal/wsd"
 )
 
+// subcommands are run instead of the relay when named by the first argument.
+var subcommands = map[string]func(args []string) error{
+	"interfaces": interfacesCommand,
+	"monitor":    monitorCommand,
+	"search":     searchCommand,
+}
+
 func main() {
-	var ifList []net.Interface
-	var err error
 	if len(os.Args) > 1 {
-		ifList, err = netutil.GetInterfaces(netutil.WithNames(os.Args[1:]...))
-		if len(ifList) != len(os.Args)-1 {
-			log.Fatalln("error: one or more requested interfaces were not found")
+		if cmd, found := subcommands[os.Args[1]]; found {
+			if err := cmd(os.Args[2:]); err != nil {
+				fatal(os.Args[1]+" failed", "error", err)
+			}
+			return
 		}
-	} else {
-		ifList, err = netutil.GetInterfaces(
-			netutil.IsNotLoopback(), netutil.IsUp(), netutil.HasIPv4Address(), netutil.HasNoPublicIPv4Address(),
-		)
 	}
+
+	configFile := flag.String("config", "", "path to a configuration file of \"flag = value\" lines")
+	interfaceQuery := flag.String("interface-query", "", "query selecting the interfaces to relay between, e.g. 'up and name ~ \"^vlan\"' (default: up, not loopback, private IPv4)")
+	logSink := flag.String("log-sink", "stderr", "log destination (stderr, syslog, journald)")
+	logAddress := flag.String("log-address", "", "path of the syslog or journal socket (default: platform-specific)")
+	logLevel := flag.String("log-level", "info", "minimum log level (debug, info, warn, error)")
+	logFormat := flag.String("log-format", "text", "log format for the stderr sink (text, json)")
+	logSampleWindow := flag.Duration("log-sample-window", 10*time.Second, "interval over which repeated log messages are summarized (0 to disable)")
+	searchWindow := flag.Duration("search-window", 5*time.Second, "window over which M-SEARCH limits are applied")
+	searchLimitSource := flag.Int("search-limit-source", 10, "maximum M-SEARCH requests relayed per source per window (0 for no limit)")
+	searchLimitST := flag.Int("search-limit-st", 20, "maximum M-SEARCH requests relayed per search target per window (0 for no limit)")
+	allowPublicSearch := flag.Bool("allow-public-search", false, "relay M-SEARCH requests from public source addresses (any but loopback, link-local, RFC 1918 and unique local, so including CGNAT)")
+	searchProxy := flag.Bool("search-proxy", false, "send M-SEARCH requests to known UPnP 1.1+ devices by unicast instead of multicast")
+	unicastSearchPort := flag.Int("unicast-search-port", 0, "port on which to accept unicast M-SEARCH requests addressed to the relay (default: disabled)")
+	relaySSDP := flag.Bool("ssdp", true, "relay SSDP")
+	relayMDNS := flag.Bool("mdns", false, "reflect mDNS")
+	mdnsServices := flag.String("mdns-services", "", "comma-separated DNS-SD service types to reflect, e.g. _googlecast._tcp,_airplay._tcp (default: all)")
+	relayWSD := flag.Bool("wsd", false, "relay WS-Discovery")
+	wsdTypes := flag.String("wsd-types", "", "comma-separated WS-Discovery types to relay, e.g. PrintDeviceType,ScanDeviceType (default: all)")
+	wsdScopes := flag.String("wsd-scopes", "", "comma-separated WS-Discovery scope prefixes to relay (default: all)")
+	wsdDedupeWindow := flag.Duration("wsd-dedupe-window", 10*time.Second, "how long to suppress repeated WS-Discovery messages with the same ID")
+	broadcastPorts := flag.String("broadcast-ports", "", "comma-separated UDP ports on which to relay IPv4 broadcasts (excluding 7 and 9 with -wol)")
+	relayWoL := flag.Bool("wol", false, "relay Wake-on-LAN magic packets")
+	wolSource := flag.String("wol-source", "", "interface on which to accept Wake-on-LAN magic packets")
+	wolTargets := flag.String("wol-targets", "", "comma-separated interfaces to which magic packets are relayed")
+	wolMACs := flag.String("wol-macs", "", "comma-separated hardware addresses that may be woken (default: any)")
+	wolRateWindow := flag.Duration("wol-rate-window", 10*time.Second, "window over which the Wake-on-LAN rate limit is applied")
+	wolRateLimit := flag.Int("wol-rate-limit", 5, "maximum magic packets relayed per hardware address per window")
+	var ipv6Scopes stringList
+	flag.Var(&ipv6Scopes, "ssdp-ipv6-scope", "SSDP IPv6 scope to relay, as link|site|organization[/hoplimit][:interface,...] (may be repeated; default: link)")
+	var protocolSpecs stringList
+	flag.Var(&protocolSpecs, "protocol", "additional multicast protocol to relay, as name=group[,group...][,limit=N] (may be repeated)")
+	fetchDescriptions := flag.Bool("fetch-descriptions", false, "fetch UPnP device descriptions to identify devices by name")
+	descriptionTimeout := flag.Duration("description-timeout", 5*time.Second, "timeout for fetching a device description")
+	descriptionMaxSize := flag.Int64("description-max-size", 256*1024, "maximum size of a device description, in bytes")
+	adminAddress := flag.String("admin-address", "", "address on which to serve the read-only admin API, e.g. 127.0.0.1:8900 (default: disabled)")
+	runAsUser := flag.String("user", "", "user to switch to once sockets are open")
+	runAsGroup := flag.String("group", "", "group to switch to once sockets are open (default: the user's primary group)")
+	allowRoot := flag.Bool("allow-root", false, "keep running as root if -user is not specified; without it, the relay refuses to run as root")
+	flag.Parse()
+
+	if *configFile != "" {
+		if err := loadConfig(flag.CommandLine, *configFile); err != nil {
+			fatal("error loading configuration", "error", err)
+		}
+	}
+
+	sink, err := logging.ParseSink(*logSink)
+	if err != nil {
+		fatal("invalid configuration", "error", err)
+	}
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		fatal("invalid configuration", "error", err)
+	}
+	format, err := logging.ParseFormat(*logFormat)
+	if err != nil {
+		fatal("invalid configuration", "error", err)
+	}
+	h, logCloser, err := logging.Open(logging.Options{
+		Sink:         sink,
+		Address:      *logAddress,
+		Tag:          "forward-ssdp",
+		Format:       format,
+		Level:        level,
+		SampleWindow: *logSampleWindow,
+	})
 	if err != nil {
-		log.Fatalf("error: %s\n", err.Error())
+		fatal("error opening log", "error", err)
 	}
+	closeLog = logCloser.Close
+	defer logCloser.Close()
+	slog.SetDefault(slog.New(h))
 
-	if len(ifList) == 0 {
-		log.Fatalf("No interfaces matched the specified criteria.")
+	ifList, err := selectInterfaces(flag.Args(), *interfaceQuery)
+	if err != nil {
+		fatal("error selecting interfaces", "error", err)
 	}
 
 	for _, ifi := range ifList {
-		log.Printf("Listening on %s (%s)\n", ifi.Name, ifi.HardwareAddr.String())
+		slog.Info("listening", "interface", netutil.Describe(ifi))
 	}
 
-	r, err := ssdp.NewRelay(ifList, ifList)
+	var protocols []ssdp.Protocol
+	if *relaySSDP {
+		searchOpts := []ssdp.SSDPOption{ssdp.WithSearchLimits(*searchWindow, *searchLimitSource, *searchLimitST)}
+		if *allowPublicSearch {
+			searchOpts = append(searchOpts, ssdp.AllowPublicSearchSources())
+		}
+		policy, err := ssdp.NewSSDPPolicy(searchOpts...)
+		if err != nil {
+			fatal("invalid configuration", "error", err)
+		}
+		groups, err := parseSSDPIPv6Scopes(ipv6Scopes)
+		if err != nil {
+			fatal("invalid configuration", "error", err)
+		}
+		protocols = append(protocols, ssdp.SSDP(policy, groups...))
+	}
+	if *relayMDNS {
+		policy, err := mdns.NewPolicy(splitList(*mdnsServices)...)
+		if err != nil {
+			fatal("invalid configuration", "error", err)
+		}
+		protocols = append(protocols, mdns.Protocol(policy))
+	}
+	if *relayWSD {
+		policy, err := wsd.NewPolicy(
+			wsd.WithTypes(splitList(*wsdTypes)...),
+			wsd.WithScopes(splitList(*wsdScopes)...),
+			wsd.WithDedupeWindow(*wsdDedupeWindow),
+		)
+		if err != nil {
+			fatal("invalid configuration", "error", err)
+		}
+		protocols = append(protocols, wsd.Protocol(policy))
+	}
+	if *broadcastPorts != "" {
+		ports, err := parsePorts(*broadcastPorts)
+		if err != nil {
+			fatal("invalid configuration", "error", err)
+		}
+		protocols = append(protocols, ssdp.Broadcast("broadcast", ports...))
+	}
+	if *relayWoL {
+		policy, err := wol.NewPolicy(*wolSource, splitList(*wolTargets),
+			wol.WithMACs(splitList(*wolMACs)...),
+			wol.WithRateLimit(*wolRateWindow, *wolRateLimit),
+			wol.WithInterfaces(interfaceNames(ifList)...),
+		)
+		if err != nil {
+			fatal("invalid configuration", "error", err)
+		}
+		protocols = append(protocols, wol.Protocol(policy))
+	}
+	for _, spec := range protocolSpecs {
+		p, err := ssdp.ParseProtocol(spec)
+		if err != nil {
+			fatal("invalid configuration", "error", err)
+		}
+		protocols = append(protocols, p)
+	}
+	if len(protocols) == 0 {
+		fatal("no protocols to relay")
+	}
+	opts := []ssdp.RelayOption{ssdp.WithProtocols(protocols...)}
+	if *searchProxy {
+		opts = append(opts, ssdp.WithSearchProxy())
+	}
+	if *fetchDescriptions {
+		opts = append(opts, ssdp.WithDescriptions(*descriptionTimeout, *descriptionMaxSize))
+	}
+	if *unicastSearchPort != 0 {
+		opts = append(opts, ssdp.WithUnicastSearch(*unicastSearchPort))
+	}
+
+	watchdog, err := systemd.WatchdogInterval()
 	if err != nil {
-		log.Fatalf("error: %s\n", err.Error())
+		slog.Warn("ignoring watchdog configuration", "error", err)
 	}
+	opts = append(opts, ssdp.WithHeartbeat(heartbeatInterval(watchdog), func(s ssdp.Stats) {
+		state := []string{systemd.Status(statusLine(s))}
+		if watchdog > 0 {
+			state = append(state, systemd.Watchdog)
+		}
+		notify(state...)
+	}))
+
+	r, err := ssdp.NewRelay(ifList, ifList, opts...)
+	if err != nil {
+		fatal("error starting relay", "error", err)
+	}
+
+	var adminListener net.Listener
+	if *adminAddress != "" {
+		adminListener, err = net.Listen("tcp", *adminAddress)
+		if err != nil {
+			fatal("error starting admin API", "error", err)
+		}
+	}
+
+	if err := privdrop.Drop(*runAsUser, *runAsGroup); err != nil {
+		fatal("error dropping privileges", "error", err)
+	}
+	if privdrop.IsRoot() && !*allowRoot {
+		fatal("refusing to run as root; specify -user or -allow-root")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		slog.Info("shutting down")
+		notify(systemd.Stopping)
+		_ = r.Close()
+	}()
+
+	if adminListener != nil {
+		srv := &http.Server{Handler: admin.NewHandler(r), ReadHeaderTimeout: 5 * time.Second}
+		defer srv.Close()
+		go func() {
+			if err := srv.Serve(adminListener); err != nil && err != http.ErrServerClosed {
+				slog.Error("error serving admin API", "error", err)
+			}
+		}()
+		slog.Info("serving admin API", "address", adminListener.Addr().String())
+	}
+
+	notify(systemd.Ready, systemd.Status(statusLine(r.Stats())))
 
 	err = r.Serve()
+	for name, s := range r.StatsByProtocol() {
+		slog.Info("relay statistics", "protocol", name, "received", s.Received, "relayed", s.Relayed, "dropped", s.Dropped)
+	}
 	if err != nil {
-		log.Fatalf("error: %s\n", err.Error())
+		fatal("error relaying packets", "error", err)
+	}
+}
+
+// selectInterfaces returns the named interfaces, the interfaces matching query (see
+// netutil.ParseQuery) or, if neither is given, the interfaces matching defaultInterfaceQuery.
+func selectInterfaces(names []string, query string) ([]net.Interface, error) {
+	if len(names) > 0 && query != "" {
+		return nil, errors.New("interface names and a query cannot both be given")
+	}
+
+	params := defaultInterfaceQuery()
+	if query != "" {
+		q, err := netutil.ParseQuery(query)
+		if err != nil {
+			return nil, err
+		}
+		params = []netutil.QueryParam{q}
+	}
+
+	if len(names) > 0 {
+		ifList, err := netutil.GetInterfaces(netutil.WithNames(names...))
+		if err != nil {
+			return nil, err
+		}
+		if len(ifList) != len(names) {
+			return nil, errors.New("one or more requested interfaces were not found")
+		}
+		return ifList, nil
+	}
+
+	ifList, err := netutil.GetInterfaces(params...)
+	if err != nil {
+		return nil, err
+	}
+	if len(ifList) == 0 {
+		return nil, errors.New("no interfaces matched the specified criteria")
+	}
+	return ifList, nil
+}
+
+// defaultInterfaceQuery selects the interfaces to use when none are named: those that are up, not
+// loopback, and on private IPv4 networks. CGNAT (100.64.0.0/10) addresses count as public, so an
+// ISP-facing interface behind carrier-grade NAT is not selected.
+func defaultInterfaceQuery() []netutil.QueryParam {
+	return []netutil.QueryParam{
+		netutil.IsNotLoopback(), netutil.IsUp(), netutil.HasIPv4Address(), netutil.HasNoPublicIPv4Address(),
+	}
+}
+
+// heartbeatInterval returns how often to report status to the service manager: twice per watchdog
+// timeout, as recommended by sd_watchdog_enabled(3), or every 30 seconds without a watchdog.
+func heartbeatInterval(watchdog time.Duration) time.Duration {
+	if watchdog > 0 {
+		return watchdog / 2
+	}
+	return 30 * time.Second
+}
+
+func statusLine(s ssdp.Stats) string {
+	return fmt.Sprintf("received %d packets, relayed %d, dropped %d", s.Received, s.Relayed, s.Dropped)
+}
+
+func notify(state ...string) {
+	if _, err := systemd.Notify(state...); err != nil {
+		slog.Warn("error notifying service manager", "error", err)
 	}
 }
+
+// closeLog flushes and closes the log sink. fatal calls it because deferred calls do not run when
+// the process exits.
+var closeLog = func() error { return nil }
+
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	_ = closeLog()
+	os.Exit(1)
+}
+
+// parseSSDPIPv6Scopes parses the values of -ssdp-ipv6-scope.
+func parseSSDPIPv6Scopes(specs []string) ([]ssdp.Group, error) {
+	var groups []ssdp.Group
+	for _, spec := range specs {
+		g, err := ssdp.ParseSSDPIPv6Scope(spec)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+func interfaceNames(ifs []net.Interface) []string {
+	names := make([]string, len(ifs))
+	for i, ifi := range ifs {
+		names[i] = ifi.Name
+	}
+	return names
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(s string) []string {
+	var l []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+func parsePorts(s string) ([]int, error) {
+	var ports []int
+	for _, e := range splitList(s) {
+		p, err := strconv.Atoi(e)
+		if err != nil || p < 1 || p > 65535 {
+			return nil, fmt.Errorf("invalid port: %q", e)
+		}
+		ports = append(ports, p)
+	}
+	return ports, nil
+}
+
+// stringList is a flag that may be repeated to build a list of values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+// interfaceColors are the ANSI colors used to tell interfaces apart in monitor output.
+var interfaceColors = []string{"\x1b[36m", "\x1b[33m", "\x1b[35m", "\x1b[32m", "\x1b[34m", "\x1b[31m"}
+
+const colorReset = "\x1b[0m"
+
+type monitorEvent struct {
+	Time      time.Time         `json:"time"`
+	Interface string            `json:"interface"`
+	Network   string            `json:"network"`
+	Source    string            `json:"source"`
+	Type      string            `json:"type"`
+	Headers   map[string]string `json:"headers"`
+}
+
+type monitorFilter struct {
+	st     string
+	nt     string
+	source *net.IPNet
+}
+
+// monitorCommand joins the SSDP groups on the selected interfaces and prints the traffic received,
+// without relaying anything.
+func monitorCommand(args []string) error {
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	var ifNames stringList
+	fs.Var(&ifNames, "i", "interface to monitor (may be repeated; default: the interfaces the relay would use)")
+	st := fs.String("st", "", "only show messages whose ST contains this string")
+	nt := fs.String("nt", "", "only show messages whose NT contains this string")
+	source := fs.String("source", "", "only show messages from this address or CIDR block")
+	query := fs.String("interface-query", "", "query selecting the interfaces to use, as for the relay")
+	var ipv6Scopes stringList
+	fs.Var(&ipv6Scopes, "ssdp-ipv6-scope", "SSDP IPv6 scope to join, as for the relay (may be repeated; default: link)")
+	asJSON := fs.Bool("json", false, "print one JSON object per message")
+	noColor := fs.Bool("no-color", false, "do not color output by interface")
+	_ = fs.Parse(args)
+
+	filter := monitorFilter{st: *st, nt: *nt}
+	if *source != "" {
+		var err error
+		filter.source, err = parseSourceFilter(*source)
+		if err != nil {
+			return err
+		}
+	}
+	groups, err := parseSSDPIPv6Scopes(ipv6Scopes)
+	if err != nil {
+		return err
+	}
+	ifs, err := selectInterfaces(splitList(strings.Join(ifNames, ",")), *query)
+	if err != nil {
+		return err
+	}
+
+	colors := make(map[string]string)
+	if !*noColor && isTerminal(os.Stdout) {
+		for i, ifi := range ifs {
+			colors[ifi.Name] = interfaceColors[i%len(interfaceColors)]
+		}
+	}
+
+	protocol := ssdp.SSDP(nil, groups...)
+	var listeners []ssdp.Listener
+	for _, ifi := range ifs {
+		ls, err := ssdp.NewProtocolListeners(ifi, protocol)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return fmt.Errorf("listening on %s: %w", ifi.Name, err)
+		}
+		listeners = append(listeners, ls...)
+	}
+
+	msgs := make(chan ssdp.Message, len(listeners)*2)
+	errs := make(chan error, len(listeners))
+	wg := sync.WaitGroup{}
+	for _, l := range listeners {
+		wg.Add(1)
+		go l.Listen(msgs, errs, &wg)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var loopErr error
+loop:
+	for {
+		select {
+		case m := <-msgs:
+			e, ok := filter.apply(time.Now(), m)
+			if ok {
+				writeMonitorEvent(os.Stdout, e, *asJSON, colors[m.IfName])
+			}
+		case loopErr = <-errs:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+
+	for _, l := range listeners {
+		_ = l.Close()
+	}
+	go func() {
+		for range msgs {
+		}
+	}()
+	wg.Wait()
+	close(msgs)
+
+	return loopErr
+}
+
+func parseSourceFilter(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source filter: %w", err)
+		}
+		return ipNet, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid source filter: %q", s)
+	}
+	bits := 8 * len(ip)
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// apply decodes m and reports whether it passes the filter.
+func (f monitorFilter) apply(now time.Time, m ssdp.Message) (monitorEvent, bool) {
+	if f.source != nil {
+		if ip, _ := m.Source(); ip == nil || !f.source.Contains(ip) {
+			return monitorEvent{}, false
+		}
+	}
+
+	e := monitorEvent{
+		Time:      now,
+		Interface: m.IfName,
+		Network:   m.Network,
+		Source:    m.SourceIP.String(),
+		Type:      ssdp.UnknownMessage.String(),
+	}
+	p, err := ssdp.ParsePacket(m.Data)
+	if err == nil {
+		e.Type = p.Type.String()
+		e.Headers = p.Headers
+	}
+
+	if f.st != "" && !strings.Contains(p.ST(), f.st) {
+		return monitorEvent{}, false
+	}
+	if f.nt != "" && !strings.Contains(p.NT(), f.nt) {
+		return monitorEvent{}, false
+	}
+	return e, true
+}
+
+// monitorSummaryHeaders are shown, in this order, in text output.
+var monitorSummaryHeaders = []string{"ST", "NT", "NTS", "USN", "LOCATION", "SERVER"}
+
+func writeMonitorEvent(w io.Writer, e monitorEvent, asJSON bool, color string) {
+	if asJSON {
+		_ = json.NewEncoder(w).Encode(e)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(color)
+	fmt.Fprintf(&b, "%s %s %s %s", e.Time.Format("15:04:05.000"), e.Interface, e.Source, e.Type)
+	for _, h := range monitorSummaryHeaders {
+		if v := e.Headers[h]; v != "" {
+			fmt.Fprintf(&b, " %s=%s", strings.ToLower(h), v)
+		}
+	}
+	if color != "" {
+		b.WriteString(colorReset)
+	}
+	b.WriteString("\n")
+	_, _ = io.WriteString(w, b.String())
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
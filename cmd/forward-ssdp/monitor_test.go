@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+func TestMonitorFilter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := ssdp.Message{
+		Network:  "udp4",
+		IfName:   "vlan30",
+		SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.30.20"), Port: 1900},
+		Data:     []byte("NOTIFY * HTTP/1.1\r\nNT: upnp:rootdevice\r\nNTS: ssdp:alive\r\nUSN: uuid:1234::upnp:rootdevice\r\n\r\n"),
+	}
+	source, err := parseSourceFilter("192.168.30.0/24")
+	assert.Nil(t, err)
+
+	e, ok := monitorFilter{nt: "rootdevice", source: source}.apply(now, m)
+	assert.True(t, ok)
+	assert.Equal(t, "NOTIFY", e.Type)
+	assert.Equal(t, "ssdp:alive", e.Headers["NTS"])
+
+	_, ok = monitorFilter{st: "ssdp:all"}.apply(now, m)
+	assert.False(t, ok)
+
+	source, err = parseSourceFilter("192.168.30.21")
+	assert.Nil(t, err)
+	_, ok = monitorFilter{source: source}.apply(now, m)
+	assert.False(t, ok)
+
+	var buf bytes.Buffer
+	writeMonitorEvent(&buf, e, false, "")
+	assert.Equal(t, "03:04:05.000 vlan30 192.168.30.20:1900 NOTIFY nt=upnp:rootdevice nts=ssdp:alive usn=uuid:1234::upnp:rootdevice\n", buf.String())
+}
+
+func TestParseSourceFilter_Invalid(t *testing.T) {
+	for _, s := range []string{"", "host", "192.168.1.0/33"} {
+		_, err := parseSourceFilter(s)
+		assert.NotNil(t, err, s)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/net/ipv4"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+type searchResult struct {
+	Interface string `json:"interface"`
+	Source    string `json:"source"`
+	ST        string `json:"st"`
+	USN       string `json:"usn"`
+	Location  string `json:"location"`
+	Server    string `json:"server"`
+}
+
+// searchCommand multicasts an M-SEARCH request on each selected interface and prints the responses.
+func searchCommand(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	var ifNames stringList
+	fs.Var(&ifNames, "i", "interface on which to search (may be repeated; default: the interfaces the relay would use)")
+	st := fs.String("st", "ssdp:all", "search target")
+	mx := fs.Int("mx", 2, "maximum number of seconds devices may wait before responding (1-5)")
+	query := fs.String("interface-query", "", "query selecting the interfaces to use, as for the relay")
+	asJSON := fs.Bool("json", false, "print responses as JSON")
+	_ = fs.Parse(args)
+
+	if *mx < 1 || *mx > 5 {
+		return fmt.Errorf("invalid MX: %d", *mx)
+	}
+	ifs, err := selectInterfaces(splitList(strings.Join(ifNames, ",")), *query)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var results []searchResult
+	var errs []error
+	var wg sync.WaitGroup
+	for _, ifi := range ifs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := search(ifi, *st, *mx)
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, r...)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("searching on %s: %w", ifi.Name, err))
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(results, func(a, b searchResult) int {
+		return strings.Compare(a.Interface+" "+a.Source+" "+a.USN, b.Interface+" "+b.Source+" "+b.USN)
+	})
+	if err := writeSearchResults(os.Stdout, results, *asJSON); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// search sends an M-SEARCH request from ifi's first IPv4 address and collects the responses that
+// arrive within mx seconds, plus a second's grace. Repeated responses are reported once.
+func search(ifi net.Interface, st string, mx int) ([]searchResult, error) {
+	ip, err := firstIPv4(ifi)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket("udp4", net.JoinHostPort(ip.String(), "0"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	pc := ipv4.NewPacketConn(conn)
+	if err := pc.SetMulticastInterface(&ifi); err != nil {
+		return nil, fmt.Errorf("setting multicast interface: %w", err)
+	}
+	if err := pc.SetMulticastTTL(2); err != nil {
+		return nil, fmt.Errorf("setting multicast TTL: %w", err)
+	}
+
+	group := ssdp.SSDP(nil).Groups[0]
+	if _, err := conn.WriteTo(ssdp.NewSearchRequest(group, st, mx), group.Addr); err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(time.Duration(mx+1) * time.Second)); err != nil {
+		return nil, err
+	}
+
+	var results []searchResult
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return results, nil
+		} else if err != nil {
+			return results, err
+		}
+
+		p, err := ssdp.ParsePacket(buf[:n])
+		if err != nil || p.Type != ssdp.ResponseMessage {
+			continue
+		}
+		r := searchResult{
+			Interface: ifi.Name,
+			Source:    addr.String(),
+			ST:        p.ST(),
+			USN:       p.USN(),
+			Location:  p.Location(),
+			Server:    p.Get("SERVER"),
+		}
+		if !slices.Contains(results, r) {
+			results = append(results, r)
+		}
+	}
+}
+
+func firstIPv4(ifi net.Interface) (net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("listing addresses: %w", err)
+	}
+	for _, a := range addrs {
+		if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, errors.New("no IPv4 address")
+}
+
+func writeSearchResults(w io.Writer, results []searchResult, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if results == nil {
+			results = []searchResult{}
+		}
+		return enc.Encode(results)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "INTERFACE\tSOURCE\tST\tUSN\tLOCATION\tSERVER")
+	for _, r := range results {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Interface, r.Source, r.ST, r.USN, r.Location, r.Server)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSearchResults(t *testing.T) {
+	results := []searchResult{{
+		Interface: "vlan30",
+		Source:    "192.168.30.20:1900",
+		ST:        "roku:ecp",
+		USN:       "uuid:roku:ecp:1234",
+		Location:  "http://192.168.30.20:8060/",
+		Server:    "Roku/9.0 UPnP/1.0",
+	}}
+
+	var buf bytes.Buffer
+	assert.Nil(t, writeSearchResults(&buf, results, false))
+	assert.Equal(t,
+		"INTERFACE  SOURCE              ST        USN                 LOCATION                    SERVER\n"+
+			"vlan30     192.168.30.20:1900  roku:ecp  uuid:roku:ecp:1234  http://192.168.30.20:8060/  Roku/9.0 UPnP/1.0\n",
+		buf.String())
+
+	buf.Reset()
+	assert.Nil(t, writeSearchResults(&buf, nil, true))
+	assert.Equal(t, "[]\n", buf.String())
+}
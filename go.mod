@@ -8,11 +8,11 @@ require (
 	github.com/google/gopacket v1.1.19
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/net v0.39.0
+	golang.org/x/sys v0.32.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.32.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/edutko/go-forward-ssdp/internal/netutil"
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+// Source provides the state served by the admin API. ssdp.Relay implements it.
+type Source interface {
+	Devices() []ssdp.Device
+	StatsByProtocol() map[string]ssdp.Stats
+	DeviceStats() []ssdp.DeviceStats
+	Interfaces() []netutil.InterfaceInfo
+}
+
+// NewHandler returns a read-only JSON API over src:
+//
+//	GET /devices        known UPnP devices, with their boot and configuration history
+//	GET /stats          packet counts by protocol
+//	GET /stats/devices  SSDP packet counts by device, labeled with the device's friendly name
+//	GET /interfaces     the interfaces in use, with their current addresses
+func NewHandler(src Source) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /devices", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, src.Devices())
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, src.StatsByProtocol())
+	})
+	mux.HandleFunc("GET /stats/devices", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, src.DeviceStats())
+	})
+	mux.HandleFunc("GET /interfaces", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, src.Interfaces())
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Warn("error writing admin response", "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edutko/go-forward-ssdp/internal/netutil"
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+type fakeSource struct {
+	devices    []ssdp.Device
+	stats      map[string]ssdp.Stats
+	devStats   []ssdp.DeviceStats
+	interfaces []netutil.InterfaceInfo
+}
+
+func (s fakeSource) Devices() []ssdp.Device {
+	return s.devices
+}
+
+func (s fakeSource) StatsByProtocol() map[string]ssdp.Stats {
+	return s.stats
+}
+
+func (s fakeSource) DeviceStats() []ssdp.DeviceStats {
+	return s.devStats
+}
+
+func (s fakeSource) Interfaces() []netutil.InterfaceInfo {
+	return s.interfaces
+}
+
+func TestHandler_Devices(t *testing.T) {
+	src := fakeSource{devices: []ssdp.Device{{
+		UUID:     "uuid:1234",
+		IfName:   "eth1",
+		BootID:   7,
+		ConfigID: 2,
+		History:  []ssdp.DeviceEvent{{Kind: ssdp.DeviceRestarted, BootID: 7, ConfigID: 2}},
+	}}}
+
+	rec := httptest.NewRecorder()
+	NewHandler(src).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/devices", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var devices []map[string]any
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &devices))
+	assert.Len(t, devices, 1)
+	assert.Equal(t, "uuid:1234", devices[0]["uuid"])
+	assert.Equal(t, float64(7), devices[0]["bootId"])
+	assert.Equal(t, "restarted", devices[0]["history"].([]any)[0].(map[string]any)["kind"])
+}
+
+func TestHandler_Stats(t *testing.T) {
+	src := fakeSource{stats: map[string]ssdp.Stats{"ssdp": {Received: 3, Relayed: 2, Dropped: 1}}}
+
+	rec := httptest.NewRecorder()
+	NewHandler(src).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"ssdp": {"received": 3, "relayed": 2, "dropped": 1}}`, rec.Body.String())
+}
+
+func TestHandler_DeviceStats(t *testing.T) {
+	src := fakeSource{devStats: []ssdp.DeviceStats{{
+		UUID:         "uuid:1234",
+		IfName:       "eth1",
+		Network:      "udp4",
+		FriendlyName: "Living Room TV",
+		Stats:        ssdp.Stats{Received: 3, Relayed: 2, Dropped: 1},
+	}}}
+
+	rec := httptest.NewRecorder()
+	NewHandler(src).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/devices", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"uuid": "uuid:1234", "interface": "eth1", "network": "udp4",
+		"friendlyName": "Living Room TV", "received": 3, "relayed": 2, "dropped": 1}]`, rec.Body.String())
+}
+
+func TestHandler_Interfaces(t *testing.T) {
+	src := fakeSource{interfaces: []netutil.InterfaceInfo{{
+		Name:    "eth1",
+		Index:   3,
+		MTU:     1500,
+		Unicast: []netutil.AddressInfo{{Prefix: "100.64.1.2/10", Class: netutil.ClassCGNAT}},
+	}}}
+
+	rec := httptest.NewRecorder()
+	NewHandler(src).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/interfaces", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var interfaces []map[string]any
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &interfaces))
+	assert.Len(t, interfaces, 1)
+	assert.Equal(t, "eth1", interfaces[0]["name"])
+	assert.Equal(t, "cgnat", interfaces[0]["unicast"].([]any)[0].(map[string]any)["class"])
+}
+
+func TestHandler_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(fakeSource{}).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/stats", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,141 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+)
+
+const defaultJournalSocketPath = "/run/systemd/journal/socket"
+
+// JournalHandler writes records to the systemd journal using its native protocol, so that
+// attributes are stored as structured fields.
+type JournalHandler struct {
+	level  slog.Leveler
+	out    *journalOutput
+	attrs  []slog.Attr
+	prefix string
+}
+
+type journalOutput struct {
+	mu   sync.Mutex
+	conn net.Conn
+	tag  string
+}
+
+// DialJournal connects to the journal's native socket at path, or at the default location if path
+// is empty.
+func DialJournal(path string, tag string, level slog.Leveler) (*JournalHandler, error) {
+	if path == "" {
+		path = defaultJournalSocketPath
+	}
+	conn, err := net.Dial("unixgram", path)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to journal: %w", err)
+	}
+	return &JournalHandler{level: level, out: &journalOutput{conn: conn, tag: tag}}, nil
+}
+
+func (h *JournalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= leveler(h.level).Level()
+}
+
+func (h *JournalHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := &bytes.Buffer{}
+	writeJournalField(buf, "MESSAGE", r.Message)
+	writeJournalField(buf, "PRIORITY", fmt.Sprint(syslogSeverity(r.Level)))
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", h.out.tag)
+	for _, a := range h.attrs {
+		writeJournalAttr(buf, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeJournalAttr(buf, h.prefix, a)
+		return true
+	})
+
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+	_, err := h.out.conn.Write(buf.Bytes())
+	return err
+}
+
+func (h *JournalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = append(h2.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return &h2
+}
+
+func (h *JournalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "_"
+	return &h2
+}
+
+func (h *JournalHandler) Close() error {
+	return h.out.conn.Close()
+}
+
+func writeJournalAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "_"
+		}
+		for _, ga := range v.Group() {
+			writeJournalAttr(buf, p, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	writeJournalField(buf, journalFieldName(prefix+a.Key), v.String())
+}
+
+// writeJournalField appends a field in the journal's native format. Values containing newlines
+// are written with an explicit length.
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName converts an attribute key to a valid journal field name: upper case letters,
+// digits and underscores, not starting with an underscore or digit.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if name == "" {
+		return "FIELD"
+	}
+	return name
+}
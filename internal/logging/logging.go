@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	TextFormat Format = "text"
+	JSONFormat Format = "json"
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case TextFormat, JSONFormat:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported log format: %q", s)
+	}
+}
+
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return l, fmt.Errorf("invalid log level: %q", s)
+	}
+	return l, nil
+}
+
+type Sink string
+
+const (
+	StderrSink  Sink = "stderr"
+	SyslogSink  Sink = "syslog"
+	JournalSink Sink = "journald"
+)
+
+func ParseSink(s string) (Sink, error) {
+	switch k := Sink(strings.ToLower(s)); k {
+	case StderrSink, SyslogSink, JournalSink:
+		return k, nil
+	default:
+		return "", fmt.Errorf("unsupported log sink: %q", s)
+	}
+}
+
+type Options struct {
+	Sink Sink
+	// Address is the path of the syslog or journal socket. If empty, the default location is used.
+	Address string
+	// Tag identifies this program in syslog and journal entries.
+	Tag    string
+	Format Format
+	Level  slog.Leveler
+	// SampleWindow is the interval over which repeated messages are summarized. A value of 0
+	// disables sampling.
+	SampleWindow time.Duration
+}
+
+// Open creates a handler for the sink specified in opts. The returned io.Closer logs any pending
+// summaries of sampled messages and releases any connection held by the handler.
+func Open(opts Options) (slog.Handler, io.Closer, error) {
+	var h slog.Handler
+	var c io.Closer = nopCloser{}
+
+	// Syslog and the journal have their own record formats.
+	if opts.Format == JSONFormat && opts.Sink != StderrSink && opts.Sink != "" {
+		return nil, nil, fmt.Errorf("log format %q is not supported with the %s sink", opts.Format, opts.Sink)
+	}
+
+	switch opts.Sink {
+	case StderrSink, "":
+		h = NewHandler(os.Stderr, opts)
+		if sh, ok := h.(*SamplingHandler); ok {
+			c = sh
+		}
+		return h, c, nil
+
+	case SyslogSink:
+		sh, err := DialSyslog(opts.Address, opts.Tag, opts.Level)
+		if err != nil {
+			return nil, nil, err
+		}
+		h, c = sh, sh
+
+	case JournalSink:
+		jh, err := DialJournal(opts.Address, opts.Tag, opts.Level)
+		if err != nil {
+			return nil, nil, err
+		}
+		h, c = jh, jh
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported log sink: %q", opts.Sink)
+	}
+
+	if opts.SampleWindow > 0 {
+		sh := NewSamplingHandler(h, opts.SampleWindow)
+		h, c = sh, closers{sh, c}
+	}
+
+	return h, c, nil
+}
+
+func NewHandler(w io.Writer, opts Options) slog.Handler {
+	hOpts := &slog.HandlerOptions{Level: opts.Level}
+
+	var h slog.Handler
+	if opts.Format == JSONFormat {
+		h = slog.NewJSONHandler(w, hOpts)
+	} else {
+		h = slog.NewTextHandler(w, hOpts)
+	}
+
+	if opts.SampleWindow > 0 {
+		h = NewSamplingHandler(h, opts.SampleWindow)
+	}
+
+	return h
+}
+
+// closers closes each of its elements in turn.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var errs []error
+	for _, c := range cs {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
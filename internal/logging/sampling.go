@@ -0,0 +1,168 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SamplingHandler passes through the first warning or error of each message class (level, message
+// and "reason" attribute) in every window and drops the rest. The number of dropped records is
+// attached to the next record of the same class that is passed through or, if there is none by
+// the end of the window, logged on its own. Records below LevelWarn are not sampled. Close logs
+// any counts still pending.
+type SamplingHandler struct {
+	next   slog.Handler
+	window time.Duration
+	state  *samplingState
+	now    func() time.Time
+}
+
+type samplingState struct {
+	mu      sync.Mutex
+	classes map[messageClass]*classState
+	// next is the handler summaries of dropped records are logged to.
+	next slog.Handler
+}
+
+type messageClass struct {
+	level   slog.Level
+	message string
+	reason  string
+}
+
+type classState struct {
+	windowStart time.Time
+	suppressed  int
+	flushTimer  *time.Timer
+}
+
+func NewSamplingHandler(next slog.Handler, window time.Duration) *SamplingHandler {
+	return &SamplingHandler{
+		next:   next,
+		window: window,
+		state:  &samplingState{classes: make(map[messageClass]*classState), next: next},
+		now:    time.Now,
+	}
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		return h.next.Handle(ctx, r)
+	}
+
+	c := messageClass{level: r.Level, message: r.Message}
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "reason" {
+			c.reason = a.Value.String()
+			return false
+		}
+		return true
+	})
+
+	suppressed, ok := h.sample(c)
+	if !ok {
+		return nil
+	}
+	if suppressed > 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Int("suppressed", suppressed))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), window: h.window, state: h.state, now: h.now}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), window: h.window, state: h.state, now: h.now}
+}
+
+// Close logs the number of records dropped from each class since the last one passed through.
+func (h *SamplingHandler) Close() error {
+	h.state.mu.Lock()
+	var pending []messageClass
+	var counts []int
+	for c, s := range h.state.classes {
+		if s.suppressed > 0 {
+			pending = append(pending, c)
+			counts = append(counts, s.suppressed)
+		}
+		h.resetClass(s)
+	}
+	h.state.mu.Unlock()
+
+	var errs []error
+	for i, c := range pending {
+		errs = append(errs, h.logSummary(c, counts[i]))
+	}
+	return errors.Join(errs...)
+}
+
+// sample reports whether a record of class c should be passed through and, if so, how many
+// records of that class were dropped since the last one that was.
+func (h *SamplingHandler) sample(c messageClass) (int, bool) {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+
+	now := h.now()
+	s, found := h.state.classes[c]
+	if !found {
+		h.state.classes[c] = &classState{windowStart: now}
+		return 0, true
+	}
+
+	if now.Sub(s.windowStart) < h.window {
+		s.suppressed++
+		if s.flushTimer == nil {
+			s.flushTimer = time.AfterFunc(s.windowStart.Add(h.window).Sub(now), func() { h.flush(c) })
+		}
+		return 0, false
+	}
+
+	suppressed := s.suppressed
+	h.resetClass(s)
+	s.windowStart = now
+	return suppressed, true
+}
+
+// flush logs the number of records of class c dropped in a window that has ended without another
+// record of the class being passed through.
+func (h *SamplingHandler) flush(c messageClass) {
+	h.state.mu.Lock()
+	s, found := h.state.classes[c]
+	if !found || s.suppressed == 0 {
+		h.state.mu.Unlock()
+		return
+	}
+	suppressed := s.suppressed
+	s.suppressed = 0
+	s.flushTimer = nil
+	h.state.mu.Unlock()
+
+	_ = h.logSummary(c, suppressed)
+}
+
+func (h *SamplingHandler) resetClass(s *classState) {
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	s.suppressed = 0
+}
+
+func (h *SamplingHandler) logSummary(c messageClass, suppressed int) error {
+	r := slog.NewRecord(h.now(), c.level, c.message, 0)
+	if c.reason != "" {
+		r.AddAttrs(slog.String("reason", c.reason))
+	}
+	r.AddAttrs(slog.Int("suppressed", suppressed))
+	return h.state.next.Handle(context.Background(), r)
+}
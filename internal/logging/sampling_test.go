@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSamplingHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	now := time.Now()
+	h := NewSamplingHandler(slog.NewTextHandler(buf, nil), time.Second)
+	h.now = func() time.Time { return now }
+	logger := slog.New(h)
+
+	for i := 0; i < 5; i++ {
+		logger.Warn("too many packets")
+	}
+	logger.Warn("something else")
+	now = now.Add(time.Second)
+	logger.Warn("too many packets")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.NotContains(t, lines[0], "suppressed")
+	assert.Contains(t, lines[1], "something else")
+	assert.Contains(t, lines[2], "suppressed=4")
+}
+
+func TestSamplingHandler_WithAttrsSharesState(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(NewSamplingHandler(slog.NewTextHandler(buf, nil), time.Hour))
+
+	logger.Warn("error relaying packet")
+	logger.With("interface", "vlan10").Warn("error relaying packet")
+
+	assert.Equal(t, 1, strings.Count(buf.String(), "error relaying packet"))
+}
+
+func TestSamplingHandler_InfoNotSampled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(NewSamplingHandler(slog.NewTextHandler(buf, nil), time.Hour))
+
+	logger.Info("listening", "interface", "vlan10")
+	logger.Info("listening", "interface", "vlan20")
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "listening"))
+}
+
+func TestNewHandler_JSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(NewHandler(buf, Options{Format: JSONFormat, Level: slog.LevelWarn}))
+
+	logger.Info("ignored")
+	logger.Warn("dropped packet", "interface", "vlan10")
+
+	assert.Equal(t, `{"level":"WARN","msg":"dropped packet","interface":"vlan10"}`,
+		strings.TrimSpace(removeTime(buf.String())))
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("debug")
+	assert.Nil(t, err)
+	assert.Equal(t, slog.LevelDebug, l)
+
+	_, err = ParseLevel("loud")
+	assert.NotNil(t, err)
+}
+
+func removeTime(s string) string {
+	start := strings.Index(s, `"time":`)
+	end := strings.Index(s, `"level"`)
+	if start < 0 || end < start {
+		return s
+	}
+	return s[:start] + s[end:]
+}
+
+func TestSamplingHandler_ReasonsSampledSeparately(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(NewSamplingHandler(slog.NewTextHandler(buf, nil), time.Hour))
+
+	logger.Warn("dropping packet", "reason", "duplicate broadcast")
+	logger.Warn("dropping packet", "reason", "source address is public")
+	logger.Warn("dropping packet", "reason", "duplicate broadcast")
+
+	assert.Equal(t, 2, strings.Count(buf.String(), "dropping packet"))
+}
+
+func TestSamplingHandler_Close(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewSamplingHandler(slog.NewTextHandler(buf, nil), time.Hour)
+	logger := slog.New(h)
+
+	for i := 0; i < 3; i++ {
+		logger.Warn("dropping packet", "reason", "duplicate broadcast", "interface", "vlan10")
+	}
+	logger.Warn("error relaying packet")
+	assert.Nil(t, h.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[2], `msg="dropping packet" reason="duplicate broadcast" suppressed=2`)
+}
+
+func TestSamplingHandler_FlushesAtEndOfWindow(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := slog.New(NewSamplingHandler(slog.NewTextHandler(buf, nil), 20*time.Millisecond))
+
+	logger.Warn("too many packets")
+	logger.Warn("too many packets")
+
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "suppressed=1")
+	}, time.Second, 5*time.Millisecond)
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogHandler(t *testing.T) {
+	path, server := listenUnixgram(t)
+
+	h, err := DialSyslog(path, "forward-ssdp", slog.LevelInfo)
+	assert.Nil(t, err)
+	defer h.Close()
+
+	logger := slog.New(h)
+	logger.Debug("ignored")
+	logger.With("interface", "vlan10").Warn("dropping M-SEARCH", "reason", "source address is public")
+
+	msg := readDatagram(t, server)
+	assert.Regexp(t, regexp.MustCompile(`^<28>\w{3} [ \d]\d \d\d:\d\d:\d\d forward-ssdp\[\d+\]: `), msg)
+	assert.True(t, strings.HasSuffix(msg,
+		`msg="dropping M-SEARCH" interface=vlan10 reason="source address is public"`), msg)
+}
+
+func TestJournalHandler(t *testing.T) {
+	path, server := listenUnixgram(t)
+
+	h, err := DialJournal(path, "forward-ssdp", slog.LevelInfo)
+	assert.Nil(t, err)
+	defer h.Close()
+
+	logger := slog.New(h)
+	logger.With("interface", "vlan10").WithGroup("packet").Error("error relaying packet",
+		"st", "ssdp:all", "error", "line one\nline two")
+
+	msg := readDatagram(t, server)
+	assert.Equal(t, "MESSAGE=error relaying packet\n"+
+		"PRIORITY=3\n"+
+		"SYSLOG_IDENTIFIER=forward-ssdp\n"+
+		"INTERFACE=vlan10\n"+
+		"PACKET_ST=ssdp:all\n"+
+		"PACKET_ERROR\n"+lengthPrefix(len("line one\nline two"))+"line one\nline two\n",
+		msg)
+}
+
+func TestJournalFieldName(t *testing.T) {
+	assert.Equal(t, "SOURCE_IP", journalFieldName("source-ip"))
+	assert.Equal(t, "PRIVATE", journalFieldName("_private"))
+	assert.Equal(t, "FIELD", journalFieldName("123"))
+}
+
+func TestOpen_UnsupportedSink(t *testing.T) {
+	_, _, err := Open(Options{Sink: "carrier-pigeon"})
+	assert.NotNil(t, err)
+}
+
+func TestOpen_JSONWithSyslog(t *testing.T) {
+	_, _, err := Open(Options{Sink: SyslogSink, Format: JSONFormat})
+	assert.ErrorContains(t, err, `log format "json" is not supported with the syslog sink`)
+}
+
+func listenUnixgram(t *testing.T) (string, *net.UnixConn) {
+	// t.TempDir() can exceed the maximum length of a unix socket path on some platforms
+	dir, err := os.MkdirTemp("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets not supported: %s", err.Error())
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return path, conn
+}
+
+func readDatagram(t *testing.T, conn *net.UnixConn) string {
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func lengthPrefix(n int) string {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(n))
+	return string(b)
+}
@@ -0,0 +1,121 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Local syslog sockets, in order of preference: Linux, FreeBSD, macOS
+var syslogSocketPaths = []string{"/dev/log", "/var/run/log", "/var/run/syslog"}
+
+const syslogFacilityDaemon = 3
+
+// SyslogHandler writes records to a local syslog daemon in RFC 3164 format.
+type SyslogHandler struct {
+	text  slog.Handler
+	level slog.Leveler
+	out   *syslogOutput
+}
+
+type syslogOutput struct {
+	mu   sync.Mutex
+	conn net.Conn
+	tag  string
+	buf  bytes.Buffer
+}
+
+// DialSyslog connects to the syslog daemon listening on the unix datagram socket at path. If path
+// is empty, the usual locations are tried.
+func DialSyslog(path string, tag string, level slog.Leveler) (*SyslogHandler, error) {
+	paths := syslogSocketPaths
+	if path != "" {
+		paths = []string{path}
+	}
+
+	var conn net.Conn
+	var err error
+	for _, p := range paths {
+		conn, err = net.Dial("unixgram", p)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connecting to syslog: %w", err)
+	}
+
+	out := &syslogOutput{conn: conn, tag: tag}
+	text := slog.NewTextHandler(&out.buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+
+	return &SyslogHandler{text: text, level: level, out: out}, nil
+}
+
+func (h *SyslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= leveler(h.level).Level()
+}
+
+func (h *SyslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+
+	h.out.buf.Reset()
+	if err := h.text.Handle(ctx, r); err != nil {
+		return err
+	}
+
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	msg := fmt.Sprintf("<%d>%s %s[%d]: %s", syslogFacilityDaemon*8+syslogSeverity(r.Level),
+		t.Format(time.Stamp), h.out.tag, os.Getpid(), bytes.TrimRight(h.out.buf.Bytes(), "\n"))
+
+	_, err := h.out.conn.Write([]byte(msg))
+	return err
+}
+
+func (h *SyslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SyslogHandler{text: h.text.WithAttrs(attrs), level: h.level, out: h.out}
+}
+
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	return &SyslogHandler{text: h.text.WithGroup(name), level: h.level, out: h.out}
+}
+
+func (h *SyslogHandler) Close() error {
+	return h.out.conn.Close()
+}
+
+func syslogSeverity(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError:
+		return 3
+	case l >= slog.LevelWarn:
+		return 4
+	case l >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
+
+func leveler(l slog.Leveler) slog.Leveler {
+	if l == nil {
+		return slog.LevelInfo
+	}
+	return l
+}
@@ -0,0 +1,166 @@
+package mdns
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+var (
+	ipv4UDPAddr = &net.UDPAddr{IP: net.ParseIP("224.0.0.251"), Port: 5353}
+	ipv6UDPAddr = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: 5353}
+)
+
+// Protocol returns an mDNS protocol description for the relay. Packets are sent with a hop limit
+// of 255, as RFC 6762, section 11 requires; the mDNS groups are link-local, so routers never
+// forward them regardless.
+func Protocol(policy *Policy) ssdp.Protocol {
+	return ssdp.Protocol{
+		Name: "mdns",
+		Groups: []ssdp.Group{
+			{Addr: ipv4UDPAddr, HopLimit: 255},
+			{Addr: ipv6UDPAddr, HopLimit: 255},
+		},
+		Policy: policy,
+	}
+}
+
+// Policy relays mDNS queries and responses that concern any of a set of DNS-SD service types.
+type Policy struct {
+	serviceTypes []string
+}
+
+// NewPolicy creates a policy that relays messages for the given service types (e.g.
+// "_googlecast._tcp"). If no service types are given, all messages are relayed.
+func NewPolicy(serviceTypes ...string) (*Policy, error) {
+	p := &Policy{}
+	for _, st := range serviceTypes {
+		st = normalizeName(st)
+		st = strings.TrimSuffix(st, ".local")
+		labels := strings.Split(st, ".")
+		if len(labels) != 2 || !strings.HasPrefix(labels[0], "_") || labels[1] != "_tcp" && labels[1] != "_udp" {
+			return nil, fmt.Errorf("invalid service type: %q", st)
+		}
+		p.serviceTypes = append(p.serviceTypes, st)
+	}
+	return p, nil
+}
+
+func (p *Policy) Inspect(m ssdp.Message) ssdp.Verdict {
+	var parser dnsmessage.Parser
+	hdr, err := parser.Start(m.Data)
+	if err != nil {
+		return ssdp.Verdict{Reason: fmt.Sprintf("error parsing DNS message: %s", err.Error()), Level: slog.LevelWarn}
+	}
+
+	names, err := messageNames(&parser)
+	if err != nil {
+		return ssdp.Verdict{Reason: fmt.Sprintf("error parsing DNS message: %s", err.Error()), Level: slog.LevelWarn}
+	}
+
+	kind := "query"
+	if hdr.Response {
+		kind = "response"
+	}
+	v := ssdp.Verdict{Attrs: []any{"type", kind, "names", summarize(names)}}
+
+	if len(p.serviceTypes) == 0 {
+		v.Relay = true
+		return v
+	}
+	for _, n := range names {
+		if p.matches(n) {
+			v.Relay = true
+			return v
+		}
+	}
+
+	v.Reason = "no allowed service types"
+	v.Level = slog.LevelDebug
+	return v
+}
+
+// matches reports whether name is one of the allowed service types, a subtype of one, or a
+// service instance of one.
+func (p *Policy) matches(name string) bool {
+	name = strings.TrimSuffix(name, ".local")
+	for _, st := range p.serviceTypes {
+		if name == st || strings.HasSuffix(name, "."+st) {
+			return true
+		}
+	}
+	return false
+}
+
+// messageNames returns the normalized names of all questions and resource records in a message,
+// along with the targets of PTR records.
+func messageNames(parser *dnsmessage.Parser) ([]string, error) {
+	var names []string
+
+	questions, err := parser.AllQuestions()
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range questions {
+		names = append(names, normalizeName(q.Name.String()))
+	}
+
+	sections := []struct {
+		header func() (dnsmessage.ResourceHeader, error)
+		skip   func() error
+	}{
+		{parser.AnswerHeader, parser.SkipAnswer},
+		{parser.AuthorityHeader, parser.SkipAuthority},
+		{parser.AdditionalHeader, parser.SkipAdditional},
+	}
+	for _, section := range sections {
+		for {
+			h, err := section.header()
+			if err == dnsmessage.ErrSectionDone {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			names = append(names, normalizeName(h.Name.String()))
+
+			if h.Type == dnsmessage.TypePTR {
+				ptr, err := parser.PTRResource()
+				if err != nil {
+					return nil, err
+				}
+				names = append(names, normalizeName(ptr.PTR.String()))
+			} else if err := section.skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return names, nil
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// summarize returns the distinct names in a message, truncated for logging.
+func summarize(names []string) string {
+	const maxNames = 5
+
+	seen := make(map[string]bool)
+	var distinct []string
+	for _, n := range names {
+		if !seen[n] {
+			seen[n] = true
+			distinct = append(distinct, n)
+		}
+	}
+	if len(distinct) > maxNames {
+		return strings.Join(distinct[:maxNames], ",") + fmt.Sprintf(",... (%d more)", len(distinct)-maxNames)
+	}
+	return strings.Join(distinct, ",")
+}
@@ -0,0 +1,108 @@
+package mdns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+func TestPolicy_Query(t *testing.T) {
+	p, _ := NewPolicy("_googlecast._tcp", "_airplay._tcp.local.")
+
+	v := p.Inspect(message(t, query("_googlecast._tcp.local.")))
+	assert.True(t, v.Relay)
+	assert.Equal(t, []any{"type", "query", "names", "_googlecast._tcp.local"}, v.Attrs)
+
+	v = p.Inspect(message(t, query("_spotify-connect._tcp.local.")))
+	assert.False(t, v.Relay)
+}
+
+func TestPolicy_Subtype(t *testing.T) {
+	p, _ := NewPolicy("_googlecast._tcp")
+
+	v := p.Inspect(message(t, query("_233637DE._sub._googlecast._tcp.local.")))
+	assert.True(t, v.Relay)
+}
+
+func TestPolicy_Response(t *testing.T) {
+	p, _ := NewPolicy("_airplay._tcp")
+
+	v := p.Inspect(message(t, ptrResponse("_airplay._tcp.local.", "Living Room._airplay._tcp.local.")))
+	assert.True(t, v.Relay)
+	assert.Equal(t, "response", v.Attrs[1])
+
+	v = p.Inspect(message(t, ptrResponse("_services._dns-sd._udp.local.", "_airplay._tcp.local.")))
+	assert.True(t, v.Relay)
+
+	v = p.Inspect(message(t, ptrResponse("_hap._tcp.local.", "Thermostat._hap._tcp.local.")))
+	assert.False(t, v.Relay)
+}
+
+func TestPolicy_AllowAll(t *testing.T) {
+	p, _ := NewPolicy()
+
+	v := p.Inspect(message(t, query("_hap._tcp.local.")))
+	assert.True(t, v.Relay)
+}
+
+func TestPolicy_Malformed(t *testing.T) {
+	p, _ := NewPolicy()
+
+	v := p.Inspect(ssdp.Message{Data: []byte("M-SEARCH * HTTP/1.1\r\n")})
+	assert.False(t, v.Relay)
+	assert.Contains(t, v.Reason, "error parsing DNS message")
+}
+
+func TestNewPolicy_InvalidServiceType(t *testing.T) {
+	for _, st := range []string{"googlecast", "_googlecast", "_googlecast._sctp", "x._googlecast._tcp"} {
+		_, err := NewPolicy(st)
+		assert.NotNil(t, err, st)
+	}
+}
+
+func query(name string) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET,
+	})
+	msg, _ := b.Finish()
+	return msg
+}
+
+func ptrResponse(name, target string) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	_ = b.StartAnswers()
+	_ = b.PTRResource(dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName(name),
+		Class: dnsmessage.ClassINET,
+		TTL:   120,
+	}, dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(target)})
+	_ = b.StartAdditionals()
+	_ = b.AResource(dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName("device.local."),
+		Class: dnsmessage.ClassINET,
+		TTL:   120,
+	}, dnsmessage.AResource{A: [4]byte{192, 168, 1, 20}})
+	msg, _ := b.Finish()
+	return msg
+}
+
+func message(t *testing.T, data []byte) ssdp.Message {
+	if len(data) == 0 {
+		t.Fatal("error building DNS message")
+	}
+	return ssdp.Message{
+		Protocol: "mdns",
+		Network:  "udp4",
+		IfName:   "vlan10",
+		SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.1.20"), Port: 5353},
+		Data:     data,
+	}
+}
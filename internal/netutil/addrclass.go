@@ -0,0 +1,172 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+)
+
+// AddressClass classifies an IP address according to the IANA IPv4 and IPv6 Special-Purpose
+// Address Registries.
+type AddressClass int
+
+const (
+	// ClassGlobalUnicast is an ordinary, globally reachable (public) unicast address.
+	ClassGlobalUnicast AddressClass = iota
+	ClassUnspecified
+	ClassLoopback
+	ClassLinkLocal
+	// ClassPrivate is an RFC 1918 address, or an IPv6 local-use translation address.
+	ClassPrivate
+	// ClassULA is an IPv6 unique local address (fc00::/7).
+	ClassULA
+	// ClassCGNAT is an address from the shared address space used by carrier-grade NAT
+	// (100.64.0.0/10).
+	ClassCGNAT
+	ClassDocumentation
+	ClassBenchmarking
+	ClassMulticast
+	// ClassReserved is any other special-purpose address that is not globally reachable.
+	ClassReserved
+)
+
+var addressClassNames = []string{
+	ClassGlobalUnicast: "global",
+	ClassUnspecified:   "unspecified",
+	ClassLoopback:      "loopback",
+	ClassLinkLocal:     "link-local",
+	ClassPrivate:       "private",
+	ClassULA:           "ula",
+	ClassCGNAT:         "cgnat",
+	ClassDocumentation: "documentation",
+	ClassBenchmarking:  "benchmarking",
+	ClassMulticast:     "multicast",
+	ClassReserved:      "reserved",
+}
+
+func (c AddressClass) String() string {
+	if c >= 0 && int(c) < len(addressClassNames) {
+		return addressClassNames[c]
+	}
+	return fmt.Sprintf("AddressClass(%d)", int(c))
+}
+
+// ParseAddressClass returns the class named s, as returned by AddressClass.String.
+func ParseAddressClass(s string) (AddressClass, error) {
+	i := slices.Index(addressClassNames, s)
+	if i < 0 {
+		return 0, fmt.Errorf("invalid address class: %q", s)
+	}
+	return AddressClass(i), nil
+}
+
+// specialPurposeBlocks lists the special-purpose address blocks, and the globally reachable
+// exceptions within them. The most specific block containing an address determines its class.
+var specialPurposeBlocks = []struct {
+	prefix netip.Prefix
+	class  AddressClass
+}{
+	{netip.MustParsePrefix("0.0.0.0/8"), ClassReserved},
+	{netip.MustParsePrefix("0.0.0.0/32"), ClassUnspecified},
+	{netip.MustParsePrefix("10.0.0.0/8"), ClassPrivate},
+	{netip.MustParsePrefix("100.64.0.0/10"), ClassCGNAT},
+	{netip.MustParsePrefix("127.0.0.0/8"), ClassLoopback},
+	{netip.MustParsePrefix("169.254.0.0/16"), ClassLinkLocal},
+	{netip.MustParsePrefix("172.16.0.0/12"), ClassPrivate},
+	{netip.MustParsePrefix("192.0.0.0/24"), ClassReserved},
+	{netip.MustParsePrefix("192.0.0.9/32"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("192.0.0.10/32"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("192.0.2.0/24"), ClassDocumentation},
+	{netip.MustParsePrefix("192.88.99.0/24"), ClassReserved},
+	{netip.MustParsePrefix("192.168.0.0/16"), ClassPrivate},
+	{netip.MustParsePrefix("198.18.0.0/15"), ClassBenchmarking},
+	{netip.MustParsePrefix("198.51.100.0/24"), ClassDocumentation},
+	{netip.MustParsePrefix("203.0.113.0/24"), ClassDocumentation},
+	{netip.MustParsePrefix("224.0.0.0/4"), ClassMulticast},
+	{netip.MustParsePrefix("240.0.0.0/4"), ClassReserved},
+	{netip.MustParsePrefix("255.255.255.255/32"), ClassReserved},
+
+	{netip.MustParsePrefix("::/128"), ClassUnspecified},
+	{netip.MustParsePrefix("::1/128"), ClassLoopback},
+	{netip.MustParsePrefix("64:ff9b:1::/48"), ClassPrivate},
+	{netip.MustParsePrefix("100::/64"), ClassReserved},
+	{netip.MustParsePrefix("2001::/23"), ClassReserved},
+	{netip.MustParsePrefix("2001::/32"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("2001:1::1/128"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("2001:1::2/128"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("2001:1::3/128"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("2001:2::/48"), ClassBenchmarking},
+	{netip.MustParsePrefix("2001:3::/32"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("2001:4:112::/48"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("2001:20::/28"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("2001:30::/28"), ClassGlobalUnicast},
+	{netip.MustParsePrefix("2001:db8::/32"), ClassDocumentation},
+	{netip.MustParsePrefix("3fff::/20"), ClassDocumentation},
+	{netip.MustParsePrefix("5f00::/16"), ClassReserved},
+	{netip.MustParsePrefix("fc00::/7"), ClassULA},
+	{netip.MustParsePrefix("fe80::/10"), ClassLinkLocal},
+	{netip.MustParsePrefix("ff00::/8"), ClassMulticast},
+}
+
+// ClassifyAddress returns the class of ip. IPv4-mapped IPv6 addresses are classified as IPv4.
+func ClassifyAddress(ip net.IP) AddressClass {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ClassReserved
+	}
+	addr = addr.Unmap()
+
+	class, bits := ClassGlobalUnicast, -1
+	for _, b := range specialPurposeBlocks {
+		if b.prefix.Bits() > bits && b.prefix.Contains(addr) {
+			class, bits = b.class, b.prefix.Bits()
+		}
+	}
+	return class
+}
+
+// HasAddressOfClass matches interfaces with an address of the given class.
+func HasAddressOfClass(class AddressClass) QueryParam {
+	return withPredicate("class = "+class.String(), func(iface net.Interface) (bool, error) {
+		addrs, err := getAddrsForInterface(iface)
+		if err != nil {
+			return false, fmt.Errorf("listing addresses: %w", err)
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ClassifyAddress(ipNet.IP) == class {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+// HasGlobalUnicastAddress matches interfaces with a public IPv4 or IPv6 address.
+func HasGlobalUnicastAddress() QueryParam {
+	return HasAddressOfClass(ClassGlobalUnicast)
+}
+
+// HasULAAddress matches interfaces with an IPv6 unique local address.
+func HasULAAddress() QueryParam {
+	return HasAddressOfClass(ClassULA)
+}
+
+// HasCGNATAddress matches interfaces with an address in the carrier-grade NAT shared address
+// space, typically those facing an ISP that does not provide a public address.
+func HasCGNATAddress() QueryParam {
+	return HasAddressOfClass(ClassCGNAT)
+}
+
+func (c AddressClass) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *AddressClass) UnmarshalText(text []byte) error {
+	parsed, err := ParseAddressClass(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
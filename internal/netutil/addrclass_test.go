@@ -0,0 +1,122 @@
+package netutil
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyAddress(t *testing.T) {
+	for ip, expected := range map[string]AddressClass{
+		"8.8.8.8":                ClassGlobalUnicast,
+		"0.0.0.0":                ClassUnspecified,
+		"0.1.2.3":                ClassReserved,
+		"10.1.2.3":               ClassPrivate,
+		"100.64.0.1":             ClassCGNAT,
+		"100.127.255.254":        ClassCGNAT,
+		"100.128.0.1":            ClassGlobalUnicast,
+		"127.0.0.1":              ClassLoopback,
+		"169.254.1.1":            ClassLinkLocal,
+		"172.31.0.1":             ClassPrivate,
+		"172.32.0.1":             ClassGlobalUnicast,
+		"192.0.0.8":              ClassReserved,
+		"192.0.0.9":              ClassGlobalUnicast,
+		"192.0.2.1":              ClassDocumentation,
+		"192.168.1.1":            ClassPrivate,
+		"198.19.0.1":             ClassBenchmarking,
+		"198.51.100.7":           ClassDocumentation,
+		"203.0.113.7":            ClassDocumentation,
+		"239.255.255.250":        ClassMulticast,
+		"240.0.0.1":              ClassReserved,
+		"255.255.255.255":        ClassReserved,
+		"::ffff:100.64.0.1":      ClassCGNAT,
+		"::":                     ClassUnspecified,
+		"::1":                    ClassLoopback,
+		"2606:4700::1111":        ClassGlobalUnicast,
+		"64:ff9b::808:808":       ClassGlobalUnicast,
+		"64:ff9b:1::1":           ClassPrivate,
+		"100::1":                 ClassReserved,
+		"2001:0:1::1":            ClassGlobalUnicast,
+		"2001:2::1":              ClassBenchmarking,
+		"2001:10::1":             ClassReserved,
+		"2001:20::1":             ClassGlobalUnicast,
+		"2001:db8::1":            ClassDocumentation,
+		"3fff:fff::1":            ClassDocumentation,
+		"fd12:3456:789a::1":      ClassULA,
+		"fe80::1":                ClassLinkLocal,
+		"ff02::c":                ClassMulticast,
+		"a64e:d060:3add:7c04::1": ClassGlobalUnicast,
+		"2002:c000:204::1":       ClassGlobalUnicast,
+		"5f00:1:2::":             ClassReserved,
+		"fec0::1":                ClassGlobalUnicast,
+	} {
+		assert.Equal(t, expected, ClassifyAddress(net.ParseIP(ip)), ip)
+	}
+	assert.Equal(t, ClassReserved, ClassifyAddress(nil))
+}
+
+func TestParseAddressClass(t *testing.T) {
+	for c := ClassGlobalUnicast; c <= ClassReserved; c++ {
+		parsed, err := ParseAddressClass(c.String())
+		assert.Nil(t, err)
+		assert.Equal(t, c, parsed)
+	}
+
+	_, err := ParseAddressClass("public")
+	assert.ErrorContains(t, err, "invalid address class")
+	assert.Equal(t, "AddressClass(99)", AddressClass(99).String())
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	for _, ip := range []string{"10.1.2.3", "172.16.0.1", "192.168.1.2", "127.0.0.1", "169.254.1.1", "::1", "fe80::1", "fd00::1"} {
+		assert.True(t, IsPrivateIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"1.1.1.1", "100.64.0.1", "100.127.255.254", "192.0.2.1", "2606:4700::1", "64:ff9b:1::1"} {
+		assert.False(t, IsPrivateIP(net.ParseIP(ip)), ip)
+	}
+}
+
+var mockGetClassAddrs = func(iface net.Interface) ([]net.Addr, error) {
+	return [][]net.Addr{
+		{mockIPAddr("127.0.0.1"), mockIPAddr("::1")},
+		{mockIPAddr("100.72.1.2"), mockIPAddr("fe80::1")},
+		{mockIPAddr("192.168.1.2"), mockIPAddr("fd00::2")},
+		{mockIPAddr("203.0.113.9"), mockIPAddr("2606:4700::1")},
+	}[iface.Index], nil
+}
+
+func TestFilterInterfaces_HasAddressOfClass(t *testing.T) {
+	getAddrsForInterface = mockGetClassAddrs
+
+	filtered, _ := FilterInterfaces(testIfs, HasCGNATAddress())
+	assert.Equal(t, []string{"en0"}, names(filtered))
+
+	filtered, _ = FilterInterfaces(testIfs, HasULAAddress())
+	assert.Equal(t, []string{"en1"}, names(filtered))
+
+	filtered, _ = FilterInterfaces(testIfs, HasGlobalUnicastAddress())
+	assert.Equal(t, []string{"utun0"}, names(filtered))
+
+	// CGNAT and documentation addresses are not private.
+	filtered, _ = FilterInterfaces(testIfs, HasNoPublicIPv4Address())
+	assert.Equal(t, []string{"lo0", "en1"}, names(filtered))
+
+	filtered, _ = FilterInterfaces(testIfs, HasAddressOfClass(ClassLinkLocal))
+	assert.Equal(t, []string{"en0"}, names(filtered))
+}
+
+// The relay's default interface query must not select an ISP-facing interface with a CGNAT
+// address.
+func TestFilterInterfaces_DefaultRelayQuery(t *testing.T) {
+	getAddrsForInterface = mockGetClassAddrs
+	ifs := []net.Interface{
+		{Index: 1, Name: "wan0", Flags: net.FlagUp},
+		{Index: 2, Name: "lan0", Flags: net.FlagUp},
+		{Index: 3, Name: "dmz0", Flags: net.FlagUp},
+	}
+
+	filtered, _ := FilterInterfaces(ifs, IsNotLoopback(), IsUp(), HasIPv4Address(), HasNoPublicIPv4Address())
+
+	assert.Equal(t, []string{"lan0"}, names(filtered))
+}
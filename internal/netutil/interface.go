@@ -1,7 +1,9 @@
 package netutil
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"strings"
 )
@@ -14,32 +16,96 @@ var InterfaceFlags = [...]net.Flags{
 	net.FlagMulticast,
 }
 
-func InterfaceToString(iface net.Interface) string {
-	flags := make([]string, 0)
+// InterfaceInfo describes an interface and its addresses. Information that could not be read is
+// left empty, and the reason recorded in Errors.
+type InterfaceInfo struct {
+	Name         string        `json:"name"`
+	Index        int           `json:"index"`
+	HardwareAddr string        `json:"hardwareAddr"`
+	MTU          int           `json:"mtu"`
+	Flags        []string      `json:"flags"`
+	Link         LinkInfo      `json:"link"`
+	Unicast      []AddressInfo `json:"unicast"`
+	Multicast    []string      `json:"multicast"`
+	Errors       []string      `json:"errors,omitempty"`
+}
+
+// AddressInfo is a unicast address of an interface, with its prefix length.
+type AddressInfo struct {
+	Prefix string       `json:"prefix"`
+	Class  AddressClass `json:"class"`
+}
+
+// Describe returns the current state of iface.
+func Describe(iface net.Interface) InterfaceInfo {
+	info := InterfaceInfo{
+		Name:         iface.Name,
+		Index:        iface.Index,
+		HardwareAddr: iface.HardwareAddr.String(),
+		MTU:          iface.MTU,
+		Flags:        []string{},
+		Unicast:      []AddressInfo{},
+		Multicast:    []string{},
+	}
 	for _, f := range InterfaceFlags {
 		if iface.Flags&f != 0 {
-			flags = append(flags, f.String())
+			info.Flags = append(info.Flags, f.String())
 		}
 	}
 
-	var unicast []string
-	addrs, err := iface.Addrs()
+	var err error
+	if info.Link, err = getLinkInfo(iface); err != nil {
+		info.Errors = append(info.Errors, err.Error())
+	}
+
+	addrs, err := getAddrsForInterface(iface)
 	if err != nil {
-		unicast = append(unicast, "error: " + err.Error())
-	} else {
-		for _, addr := range addrs {
-			unicast = append(unicast, addr.String())
+		info.Errors = append(info.Errors, fmt.Sprintf("listing addresses: %s", err))
+	}
+	for _, a := range addrs {
+		ai := AddressInfo{Prefix: a.String(), Class: ClassReserved}
+		if ipNet, ok := a.(*net.IPNet); ok {
+			ai.Class = ClassifyAddress(ipNet.IP)
 		}
+		info.Unicast = append(info.Unicast, ai)
 	}
 
-	var multicast []string
-	addrs, err = iface.MulticastAddrs()
+	addrs, err = getMulticastAddrsForInterface(iface)
 	if err != nil {
-		multicast = append(multicast, "error: " + err.Error())
-	} else {
-		for _, addr := range addrs {
-			multicast = append(multicast, addr.String())
-		}
+		info.Errors = append(info.Errors, fmt.Sprintf("listing multicast addresses: %s", err))
+	}
+	for _, a := range addrs {
+		info.Multicast = append(info.Multicast, a.String())
+	}
+
+	return info
+}
+
+// LogValue logs the interface's name, hardware address, MTU, kind and unicast addresses.
+func (info InterfaceInfo) LogValue() slog.Value {
+	var unicast []string
+	for _, a := range info.Unicast {
+		unicast = append(unicast, a.Prefix)
+	}
+	attrs := []slog.Attr{
+		slog.String("name", info.Name),
+		slog.String("mac", info.HardwareAddr),
+		slog.Int("mtu", info.MTU),
+		slog.String("kind", string(info.Link.Kind)),
+		slog.String("addresses", strings.Join(unicast, ",")),
+	}
+	if len(info.Errors) > 0 {
+		attrs = append(attrs, slog.Any("error", errors.New(strings.Join(info.Errors, "; "))))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func InterfaceToString(iface net.Interface) string {
+	info := Describe(iface)
+
+	var unicast []string
+	for _, a := range info.Unicast {
+		unicast = append(unicast, fmt.Sprintf("%s (%s)", a.Prefix, a.Class))
 	}
 
 	format := "%s (%s)\n" +
@@ -47,14 +113,22 @@ func InterfaceToString(iface net.Interface) string {
 		"  Unicast addresses:\n" +
 		"    %s\n" +
 		"  Multicast addresses:\n" +
-		"    %s\n"
-	return fmt.Sprintf(format,
-		iface.Name, iface.HardwareAddr.String(),
-		strings.Join(flags, ", "),
+		"    %s\n" +
+		"  MTU: %d, kind: %s, state: %s\n"
+	s := fmt.Sprintf(format,
+		info.Name, info.HardwareAddr,
+		strings.Join(info.Flags, ", "),
 		strings.Join(unicast, "\n    "),
-		strings.Join(multicast, "\n    "))
+		strings.Join(info.Multicast, "\n    "),
+		info.MTU, info.Link.Kind, info.Link.OperState)
+	if len(info.Errors) > 0 {
+		s += "  Errors:\n    " + strings.Join(info.Errors, "\n    ") + "\n"
+	}
+	return s
 }
 
+// GetInterfaces returns the system's interfaces that match params. With FailOnError, the error
+// lists every interface that could not be inspected.
 func GetInterfaces(params ...QueryParam) ([]net.Interface, error) {
 	ifs, err := net.Interfaces()
 	if err != nil {
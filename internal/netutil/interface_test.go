@@ -1,6 +1,7 @@
 package netutil
 
 import (
+	"encoding/json"
 	"net"
 	"testing"
 
@@ -23,3 +24,54 @@ func TestInterfaceToString(t *testing.T) {
 			"  Unicast addresses:\n",
 	)
 }
+
+// mockIPPrefix returns the address in cidr with its network's mask, as an interface reports it.
+func mockIPPrefix(cidr string) *net.IPNet {
+	ip, ipNet, _ := net.ParseCIDR(cidr)
+	ipNet.IP = ip
+	return ipNet
+}
+
+func TestDescribe(t *testing.T) {
+	getAddrsForInterface = func(iface net.Interface) ([]net.Addr, error) {
+		return []net.Addr{mockIPPrefix("127.0.0.1/8"), mockIPPrefix("::1/128")}, nil
+	}
+	getLinkInfo = mockGetLinkInfo
+	getMulticastAddrsForInterface = func(iface net.Interface) ([]net.Addr, error) {
+		return []net.Addr{&net.IPAddr{IP: net.ParseIP("239.255.255.250")}}, nil
+	}
+
+	info := Describe(testIfs[0])
+
+	assert.Equal(t, InterfaceInfo{
+		Name:         "lo0",
+		Index:        0,
+		HardwareAddr: "00:01:02:03:04:05",
+		MTU:          16384,
+		Flags:        []string{"up", "loopback", "multicast"},
+		Link:         LinkInfo{Kind: KindOther, OperState: "unknown"},
+		Unicast: []AddressInfo{
+			{Prefix: "127.0.0.1/8", Class: ClassLoopback},
+			{Prefix: "::1/128", Class: ClassLoopback},
+		},
+		Multicast: []string{"239.255.255.250"},
+	}, info)
+
+	data, err := json.Marshal(info)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"unicast":[{"prefix":"127.0.0.1/8","class":"loopback"}`)
+	var decoded InterfaceInfo
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, info, decoded)
+}
+
+func TestDescribe_Errors(t *testing.T) {
+	getAddrsForInterface = failingGetAddrsForInterface
+	getLinkInfo = mockGetLinkInfo
+
+	info := Describe(testIfs[1])
+
+	assert.Empty(t, info.Unicast)
+	assert.Equal(t, []string{"listing addresses: no such device"}, info.Errors)
+	assert.Contains(t, InterfaceToString(testIfs[1]), "  Errors:\n    listing addresses: no such device\n")
+}
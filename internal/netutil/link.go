@@ -0,0 +1,145 @@
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+)
+
+// InterfaceKind is the type of device behind a network interface.
+type InterfaceKind string
+
+const (
+	KindUnknown   InterfaceKind = "unknown"
+	KindPhysical  InterfaceKind = "physical"
+	KindVLAN      InterfaceKind = "vlan"
+	KindBridge    InterfaceKind = "bridge"
+	KindTun       InterfaceKind = "tun" // also TAP devices
+	KindWireGuard InterfaceKind = "wireguard"
+	KindOther     InterfaceKind = "other"
+)
+
+var interfaceKinds = []InterfaceKind{KindUnknown, KindPhysical, KindVLAN, KindBridge, KindTun, KindWireGuard, KindOther}
+
+// operStates are the operational states of RFC 2863, as reported by Linux.
+var operStates = []string{"unknown", "notpresent", "down", "lowerlayerdown", "testing", "dormant", "up"}
+
+// LinkInfo is link-layer information about an interface that net.Interface does not provide. It is
+// only available on Linux; elsewhere Kind and OperState are "unknown".
+type LinkInfo struct {
+	Kind InterfaceKind `json:"kind"`
+	// VLANID is the 802.1Q VLAN ID of a VLAN interface, or 0 if it is not known.
+	VLANID int `json:"vlanId,omitempty"`
+	// Parent is the name of the interface a VLAN interface is stacked on.
+	Parent    string `json:"parent,omitempty"`
+	OperState string `json:"operState"`
+}
+
+// GetLinkInfo returns link-layer information about iface.
+func GetLinkInfo(iface net.Interface) (LinkInfo, error) {
+	return getLinkInfo(iface)
+}
+
+var getLinkInfo = linkInfo
+
+// MulticastSnoopingWarning returns a warning if iface is, or is a port of, a bridge that snoops
+// IGMP/MLD but does not send queries itself, so that multicast may stop being forwarded to iface
+// unless another querier is present on the network. It returns "" if there is no such risk, or if
+// it cannot be determined on this platform.
+func MulticastSnoopingWarning(iface net.Interface) (string, error) {
+	return multicastSnoopingWarning(iface)
+}
+
+func unknownLinkInfo() LinkInfo {
+	return LinkInfo{Kind: KindUnknown, OperState: "unknown"}
+}
+
+// WithMTU matches interfaces whose MTU is mtu.
+func WithMTU(mtu int) QueryParam {
+	return WithMTURange(mtu, mtu)
+}
+
+// WithMTURange matches interfaces whose MTU is between min and max, inclusive. Use 0 or
+// math.MaxInt to leave either end unbounded.
+func WithMTURange(min, max int) QueryParam {
+	var desc string
+	switch {
+	case min == max:
+		desc = "mtu = " + strconv.Itoa(min)
+	case max == math.MaxInt:
+		desc = "mtu >= " + strconv.Itoa(min)
+	case min <= 0:
+		desc = "mtu <= " + strconv.Itoa(max)
+	default:
+		desc = fmt.Sprintf("mtu >= %d and mtu <= %d", min, max)
+	}
+	return withPredicate(desc, func(iface net.Interface) (bool, error) {
+		return iface.MTU >= min && iface.MTU <= max, nil
+	})
+}
+
+// WithKind matches interfaces of the given kind.
+func WithKind(kind InterfaceKind) QueryParam {
+	return func(q *interfaceQuery) error {
+		if !slices.Contains(interfaceKinds, kind) {
+			return fmt.Errorf("invalid interface kind: %q", kind)
+		}
+		return withLinkInfo("kind = "+string(kind), func(li LinkInfo) bool {
+			return li.Kind == kind
+		})(q)
+	}
+}
+
+// WithVLANID matches 802.1Q VLAN interfaces with the given VLAN ID. A VLAN interface whose ID
+// cannot be read is an error, handled according to the query's error policy.
+func WithVLANID(id int) QueryParam {
+	return func(q *interfaceQuery) error {
+		if id < 1 || id > 4094 {
+			return fmt.Errorf("invalid VLAN ID: %d", id)
+		}
+		return withPredicate("vlan = "+strconv.Itoa(id), func(iface net.Interface) (bool, error) {
+			li, err := getLinkInfo(iface)
+			if err != nil {
+				return false, err
+			}
+			if li.Kind == KindVLAN && li.VLANID == 0 {
+				return false, errors.New("VLAN ID is not known")
+			}
+			return li.VLANID == id, nil
+		})(q)
+	}
+}
+
+// WithParent matches VLAN interfaces stacked on the named interface.
+func WithParent(name string) QueryParam {
+	return withLinkInfo("parent = "+strconv.Quote(name), func(li LinkInfo) bool {
+		return li.Parent == name
+	})
+}
+
+// WithOperState matches interfaces in the given operational state, e.g. "up" or "lowerlayerdown".
+// Unlike IsUp, which reports whether the interface is administratively up, this reflects whether it
+// can pass traffic.
+func WithOperState(state string) QueryParam {
+	return func(q *interfaceQuery) error {
+		if !slices.Contains(operStates, state) {
+			return fmt.Errorf("invalid operational state: %q", state)
+		}
+		return withLinkInfo("operstate = "+state, func(li LinkInfo) bool {
+			return li.OperState == state
+		})(q)
+	}
+}
+
+func withLinkInfo(desc string, test func(li LinkInfo) bool) QueryParam {
+	return withPredicate(desc, func(iface net.Interface) (bool, error) {
+		li, err := getLinkInfo(iface)
+		if err != nil {
+			return false, err
+		}
+		return test(li), nil
+	})
+}
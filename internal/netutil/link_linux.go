@@ -0,0 +1,247 @@
+package netutil
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sysRoot is the directory under which sys/class/net and proc/net/vlan/config are found.
+var sysRoot = "/"
+
+func linkInfo(iface net.Interface) (LinkInfo, error) {
+	li, err := readLinkInfo(sysRoot, iface.Name)
+	if err != nil {
+		return unknownLinkInfo(), fmt.Errorf("reading link information: %w", err)
+	}
+	if li.Kind == KindVLAN && li.VLANID == 0 {
+		// /proc/net/vlan/config is only readable by root, but rtnetlink reports the VLAN ID to
+		// anyone.
+		if li.VLANID, err = netlinkVLANID(iface.Index); err != nil {
+			return li, fmt.Errorf("reading VLAN ID: %w", err)
+		}
+	}
+	return li, nil
+}
+
+func readLinkInfo(root, name string) (LinkInfo, error) {
+	dir := filepath.Join(root, "sys/class/net", name)
+	if _, err := os.Stat(dir); err != nil {
+		return LinkInfo{}, err
+	}
+
+	li := unknownLinkInfo()
+	state, err := os.ReadFile(filepath.Join(dir, "operstate"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return LinkInfo{}, err
+	} else if s := strings.TrimSpace(string(state)); s != "" {
+		li.OperState = s
+	}
+
+	devType, err := readUevent(filepath.Join(dir, "uevent"), "DEVTYPE")
+	if err != nil {
+		return LinkInfo{}, err
+	}
+	switch {
+	case devType == "vlan":
+		li.Kind = KindVLAN
+	case devType == "wireguard":
+		li.Kind = KindWireGuard
+	case devType == "bridge" || exists(filepath.Join(dir, "bridge")):
+		li.Kind = KindBridge
+	case exists(filepath.Join(dir, "tun_flags")):
+		li.Kind = KindTun
+	case exists(filepath.Join(dir, "device")):
+		li.Kind = KindPhysical
+	default:
+		li.Kind = KindOther
+	}
+
+	if li.Kind == KindVLAN {
+		// The lower_* link names the device a VLAN sits on. Bridges and bonds have lower_* links
+		// too, but those name their ports, which are not parents.
+		lower, err := filepath.Glob(filepath.Join(dir, "lower_*"))
+		if err != nil {
+			return LinkInfo{}, err
+		}
+		if len(lower) > 0 {
+			li.Parent = strings.TrimPrefix(filepath.Base(lower[0]), "lower_")
+		}
+
+		id, parent, err := readVLANConfig(filepath.Join(root, "proc/net/vlan/config"), name)
+		if err != nil {
+			return LinkInfo{}, err
+		}
+		li.VLANID = id
+		if li.Parent == "" {
+			li.Parent = parent
+		}
+	}
+	return li, nil
+}
+
+// readUevent returns the value of key in a sysfs uevent file, or "" if it is not present.
+func readUevent(path, key string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if k, v, found := strings.Cut(line, "="); found && k == key {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+// readVLANConfig finds name in the 8021q module's configuration, which looks like:
+//
+//	VLAN Dev name	 | VLAN ID
+//	Name-Type: VLAN_NAME_TYPE_RAW_PLUS_VID_NO_PAD
+//	eth0.10        | 10  | eth0
+//
+// The file is only readable by root, so if it cannot be read, the VLAN ID is left unknown for
+// linkInfo to get from rtnetlink.
+func readVLANConfig(path, name string) (int, string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return 0, "", nil
+	} else if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) != 3 || strings.TrimSpace(fields[0]) != name {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid VLAN ID in %s: %w", path, err)
+		}
+		return id, strings.TrimSpace(fields[2]), nil
+	}
+	return 0, "", scanner.Err()
+}
+
+// netlinkVLANID asks rtnetlink for the VLAN ID of the link with the given index.
+func netlinkVLANID(index int) (int, error) {
+	data, err := syscall.NetlinkRIB(syscall.RTM_GETLINK, syscall.AF_UNSPEC)
+	if err != nil {
+		return 0, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(data)
+	if err != nil {
+		return 0, err
+	}
+	return vlanIDFromLinks(msgs, index)
+}
+
+// vlanIDFromLinks finds the link with the given index in an RTM_GETLINK dump and returns the VLAN
+// ID nested in its IFLA_LINKINFO attribute.
+func vlanIDFromLinks(msgs []syscall.NetlinkMessage, index int) (int, error) {
+	for _, m := range msgs {
+		if m.Header.Type != syscall.RTM_NEWLINK || len(m.Data) < syscall.SizeofIfInfomsg {
+			continue
+		}
+		ifim := (*syscall.IfInfomsg)(unsafe.Pointer(&m.Data[0]))
+		if int(ifim.Index) != index {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(&m)
+		if err != nil {
+			return 0, err
+		}
+		for _, a := range attrs {
+			if a.Attr.Type&^unix.NLA_F_NESTED != unix.IFLA_LINKINFO {
+				continue
+			}
+			info := nestedAttrs(a.Value)
+			if kind := strings.TrimRight(string(info[unix.IFLA_INFO_KIND]), "\x00"); kind != "vlan" {
+				return 0, fmt.Errorf("link %d is not a VLAN (%q)", index, kind)
+			}
+			id := nestedAttrs(info[unix.IFLA_INFO_DATA])[unix.IFLA_VLAN_ID]
+			if len(id) < 2 {
+				break
+			}
+			return int(binary.NativeEndian.Uint16(id)), nil
+		}
+		return 0, fmt.Errorf("no VLAN ID for link %d", index)
+	}
+	return 0, fmt.Errorf("link %d not found", index)
+}
+
+// nestedAttrs parses the netlink attributes nested in b, keyed by type.
+func nestedAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= syscall.SizeofRtAttr {
+		n := int(binary.NativeEndian.Uint16(b))
+		if n < syscall.SizeofRtAttr || n > len(b) {
+			break
+		}
+		typ := binary.NativeEndian.Uint16(b[2:]) &^ (unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+		attrs[typ] = b[syscall.SizeofRtAttr:n]
+		b = b[min((n+unix.NLA_ALIGNTO-1)&^(unix.NLA_ALIGNTO-1), len(b)):]
+	}
+	return attrs
+}
+
+func multicastSnoopingWarning(iface net.Interface) (string, error) {
+	w, err := readSnoopingWarning(sysRoot, iface.Name)
+	if err != nil {
+		return "", fmt.Errorf("reading bridge configuration: %w", err)
+	}
+	return w, nil
+}
+
+func readSnoopingWarning(root, name string) (string, error) {
+	bridge := name
+	if !exists(filepath.Join(root, "sys/class/net", name, "bridge")) {
+		master, err := os.Readlink(filepath.Join(root, "sys/class/net", name, "master"))
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		bridge = filepath.Base(master)
+	}
+
+	dir := filepath.Join(root, "sys/class/net", bridge, "bridge")
+	if !exists(dir) {
+		// The master is not a bridge (e.g. a bond).
+		return "", nil
+	}
+	snooping, err := os.ReadFile(filepath.Join(dir, "multicast_snooping"))
+	if err != nil {
+		return "", err
+	}
+	querier, err := os.ReadFile(filepath.Join(dir, "multicast_querier"))
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(snooping)) == "1" && strings.TrimSpace(string(querier)) == "0" {
+		return fmt.Sprintf("bridge %s snoops IGMP/MLD but is not a querier; without another querier on "+
+			"the network it may stop forwarding multicast", bridge), nil
+	}
+	return "", nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,193 @@
+package netutil
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func writeFixture(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		if content == "" {
+			assert.Nil(t, os.MkdirAll(path, 0o755))
+		} else {
+			assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+	}
+}
+
+func TestReadLinkInfo(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"sys/class/net/igb0/operstate":     "up\n",
+		"sys/class/net/igb0/uevent":        "INTERFACE=igb0\nIFINDEX=2\n",
+		"sys/class/net/igb0/device":        "",
+		"sys/class/net/igb0.10/operstate":  "lowerlayerdown\n",
+		"sys/class/net/igb0.10/uevent":     "DEVTYPE=vlan\nINTERFACE=igb0.10\n",
+		"sys/class/net/igb0.10/lower_igb0": "",
+		"sys/class/net/br0/operstate":      "up\n",
+		"sys/class/net/br0/uevent":         "DEVTYPE=bridge\n",
+		"sys/class/net/br0/bridge":         "",
+		"sys/class/net/br0/lower_igb1":     "",
+		"sys/class/net/tap0/operstate":     "down\n",
+		"sys/class/net/tap0/tun_flags":     "0x1002\n",
+		"sys/class/net/wg0/uevent":         "DEVTYPE=wireguard\n",
+		"sys/class/net/lo/operstate":       "unknown\n",
+		"sys/class/net/vlan20/uevent":      "DEVTYPE=vlan\n",
+		"proc/net/vlan/config": "VLAN Dev name	 | VLAN ID\n" +
+			"Name-Type: VLAN_NAME_TYPE_RAW_PLUS_VID_NO_PAD\n" +
+			"igb0.10        | 10  | igb0\n" +
+			"vlan20         | 20  | igb1\n",
+	})
+
+	for name, expected := range map[string]LinkInfo{
+		"igb0":    {Kind: KindPhysical, OperState: "up"},
+		"igb0.10": {Kind: KindVLAN, VLANID: 10, Parent: "igb0", OperState: "lowerlayerdown"},
+		"br0":     {Kind: KindBridge, OperState: "up"},
+		"tap0":    {Kind: KindTun, OperState: "down"},
+		"wg0":     {Kind: KindWireGuard, OperState: "unknown"},
+		"lo":      {Kind: KindOther, OperState: "unknown"},
+		"vlan20":  {Kind: KindVLAN, VLANID: 20, Parent: "igb1", OperState: "unknown"},
+	} {
+		li, err := readLinkInfo(root, name)
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, li, name)
+	}
+}
+
+func TestReadLinkInfo_VLANConfigUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any file")
+	}
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"sys/class/net/igb0.10/uevent":     "DEVTYPE=vlan\n",
+		"sys/class/net/igb0.10/lower_igb0": "",
+		"proc/net/vlan/config":             "igb0.10        | 10  | igb0\n",
+	})
+	assert.Nil(t, os.Chmod(filepath.Join(root, "proc/net/vlan/config"), 0o000))
+
+	li, err := readLinkInfo(root, "igb0.10")
+
+	assert.Nil(t, err)
+	assert.Equal(t, LinkInfo{Kind: KindVLAN, Parent: "igb0", OperState: "unknown"}, li)
+}
+
+func TestReadLinkInfo_Missing(t *testing.T) {
+	_, err := readLinkInfo(t.TempDir(), "eth0")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadSnoopingWarning(t *testing.T) {
+	root := t.TempDir()
+	writeFixture(t, root, map[string]string{
+		"sys/class/net/br0/bridge/multicast_snooping": "1\n",
+		"sys/class/net/br0/bridge/multicast_querier":  "0\n",
+		"sys/class/net/br1/bridge/multicast_snooping": "1\n",
+		"sys/class/net/br1/bridge/multicast_querier":  "1\n",
+		"sys/class/net/br2/bridge/multicast_snooping": "0\n",
+		"sys/class/net/br2/bridge/multicast_querier":  "0\n",
+		"sys/class/net/bond0/operstate":               "up\n",
+		"sys/class/net/eth0/operstate":                "up\n",
+		"sys/class/net/eth1/operstate":                "up\n",
+		"sys/class/net/eth2/operstate":                "up\n",
+	})
+	assert.Nil(t, os.Symlink("../br0", filepath.Join(root, "sys/class/net/eth0/master")))
+	assert.Nil(t, os.Symlink("../bond0", filepath.Join(root, "sys/class/net/eth1/master")))
+
+	for name, expected := range map[string]string{
+		"br0":  "bridge br0 snoops IGMP/MLD but is not a querier",
+		"eth0": "bridge br0 snoops IGMP/MLD but is not a querier",
+		"br1":  "",
+		"br2":  "",
+		"eth1": "",
+		"eth2": "",
+	} {
+		w, err := readSnoopingWarning(root, name)
+		assert.Nil(t, err, name)
+		if expected == "" {
+			assert.Empty(t, w, name)
+		} else {
+			assert.Contains(t, w, expected, name)
+		}
+	}
+}
+
+func TestLinkInfo_Loopback(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("no loopback interface")
+	}
+	li, err := linkInfo(*lo)
+	assert.Nil(t, err)
+	assert.Equal(t, KindOther, li.Kind)
+}
+
+func netlinkAttr(typ uint16, value []byte) []byte {
+	b := make([]byte, syscall.SizeofRtAttr, syscall.SizeofRtAttr+len(value)+3)
+	binary.NativeEndian.PutUint16(b, uint16(syscall.SizeofRtAttr+len(value)))
+	binary.NativeEndian.PutUint16(b[2:], typ)
+	b = append(b, value...)
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func netlinkLink(index int32, attrs ...[]byte) syscall.NetlinkMessage {
+	data := make([]byte, syscall.SizeofIfInfomsg)
+	binary.NativeEndian.PutUint32(data[4:], uint32(index))
+	for _, a := range attrs {
+		data = append(data, a...)
+	}
+	return syscall.NetlinkMessage{
+		Header: syscall.NlMsghdr{Len: uint32(syscall.NLMSG_HDRLEN + len(data)), Type: syscall.RTM_NEWLINK},
+		Data:   data,
+	}
+}
+
+func TestVLANIDFromLinks(t *testing.T) {
+	vlanID := make([]byte, 2)
+	binary.NativeEndian.PutUint16(vlanID, 42)
+	msgs := []syscall.NetlinkMessage{
+		netlinkLink(1, netlinkAttr(syscall.IFLA_IFNAME, []byte("lo\x00"))),
+		netlinkLink(2, netlinkAttr(syscall.IFLA_IFNAME, []byte("eth0\x00"))),
+		netlinkLink(3,
+			netlinkAttr(syscall.IFLA_IFNAME, []byte("eth0.42\x00")),
+			netlinkAttr(unix.IFLA_LINKINFO|unix.NLA_F_NESTED, append(
+				netlinkAttr(unix.IFLA_INFO_KIND, []byte("vlan\x00")),
+				netlinkAttr(unix.IFLA_INFO_DATA|unix.NLA_F_NESTED, netlinkAttr(unix.IFLA_VLAN_ID, vlanID))...)),
+		),
+		netlinkLink(4,
+			netlinkAttr(unix.IFLA_LINKINFO, netlinkAttr(unix.IFLA_INFO_KIND, []byte("bridge\x00"))),
+		),
+	}
+
+	id, err := vlanIDFromLinks(msgs, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 42, id)
+
+	_, err = vlanIDFromLinks(msgs, 2)
+	assert.ErrorContains(t, err, "no VLAN ID for link 2")
+	_, err = vlanIDFromLinks(msgs, 4)
+	assert.ErrorContains(t, err, `link 4 is not a VLAN ("bridge")`)
+	_, err = vlanIDFromLinks(msgs, 5)
+	assert.ErrorContains(t, err, "link 5 not found")
+}
+
+func TestNetlinkVLANID_Loopback(t *testing.T) {
+	lo, err := net.InterfaceByName("lo")
+	if err != nil {
+		t.Skip("no loopback interface")
+	}
+	_, err = netlinkVLANID(lo.Index)
+	assert.ErrorContains(t, err, "no VLAN ID")
+}
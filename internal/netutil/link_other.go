@@ -0,0 +1,13 @@
+//go:build !linux
+
+package netutil
+
+import "net"
+
+func linkInfo(_ net.Interface) (LinkInfo, error) {
+	return unknownLinkInfo(), nil
+}
+
+func multicastSnoopingWarning(_ net.Interface) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,447 @@
+package netutil
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseQuery parses a textual interface query, such as
+//
+//	up and not loopback and (name ~ "^vlan" or cidr 10.20.0.0/16)
+//
+// The terms are:
+//
+//	up, broadcast, loopback, pointtopoint, multicast   the interface flag is set
+//	ipv4, ipv6                                         the interface has an address of that family
+//	public-ipv4, public-ipv6                           ... and it is public
+//	name = NAME                                        the interface is named NAME
+//	name ~ REGEXP                                      the interface name matches REGEXP
+//	name like PATTERN                                  the interface name matches a shell pattern
+//	name prefix PREFIX                                 the interface name begins with PREFIX
+//	mac = MAC                                          the interface has hardware address MAC
+//	mac prefix PREFIX                                  the hardware address begins with PREFIX
+//	ip = IP                                            the interface has address IP
+//	cidr CIDR                                          the interface has an address in CIDR
+//	group = IP                                         the interface has joined multicast group IP
+//	class = CLASS                                      the interface has an address of CLASS, one of
+//	                                                   global, private, cgnat, ula, link-local, etc.
+//	                                                   (see AddressClass)
+//	index = N                                          the interface index is N
+//	mtu = N, mtu < N, mtu <= N, mtu > N, mtu >= N      the interface MTU compares with N
+//	kind = KIND                                        the interface is a physical, vlan, bridge, tun,
+//	                                                   wireguard, other or unknown device
+//	vlan = ID                                          the interface is a VLAN with 802.1Q ID
+//	parent = NAME                                      the interface is stacked on NAME, e.g. a VLAN
+//	operstate = STATE                                  the operational state is STATE, e.g. up or down
+//	any                                                always matches
+//
+// Terms are combined with "not", "and" and "or", in decreasing order of precedence, and may be
+// grouped with parentheses. Values may be written as Go string literals, which is necessary if
+// they contain spaces, parentheses, '=', '~', '<', '>' or '"'. Kind, VLAN, parent and operational
+// state are only known on Linux.
+func ParseQuery(s string) (QueryParam, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	p := parser{tokens: tokens}
+	q, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = p.unexpected("\"and\", \"or\" or end of query")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	// Check the values the query contains, such as regular expressions, now rather than when it is
+	// first used.
+	if err := q(&interfaceQuery{}); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return q, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenEquals
+	tokenTilde
+	tokenCompare
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the byte offset of the token in the query.
+	pos int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+const specialChars = "()=~<>\""
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == '=':
+			tokens = append(tokens, token{tokenEquals, "=", i})
+			i++
+		case c == '~':
+			tokens = append(tokens, token{tokenTilde, "~", i})
+			i++
+		case c == '<' || c == '>':
+			end := i + 1
+			if end < len(s) && s[end] == '=' {
+				end++
+			}
+			tokens = append(tokens, token{tokenCompare, s[i:end], i})
+			i = end
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at column %d", i+1)
+			}
+			text, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at column %d: %w", i+1, err)
+			}
+			tokens = append(tokens, token{tokenString, text, i})
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\n\r"+specialChars, rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, token{tokenWord, s[i:end], i})
+			i = end
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(s)}), nil
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// prev returns the token most recently consumed.
+func (p *parser) prev() token {
+	return p.tokens[p.i-1]
+}
+
+func (p *parser) unexpected(expected string) error {
+	t := p.peek()
+	return fmt.Errorf("expected %s at column %d, found %s", expected, t.pos+1, t)
+}
+
+func (p *parser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && t.text == word
+}
+
+// parseOr parses: and ("or" and)*
+func (p *parser) parseOr() (QueryParam, error) {
+	var alternatives []QueryParam
+	for {
+		q, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, q)
+		if !p.isKeyword("or") {
+			break
+		}
+		p.next()
+	}
+
+	if len(alternatives) == 1 {
+		return alternatives[0], nil
+	}
+	return Any(alternatives...), nil
+}
+
+// parseAnd parses: unary ("and" unary)*
+func (p *parser) parseAnd() (QueryParam, error) {
+	var terms []QueryParam
+	for {
+		q, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, q)
+		if !p.isKeyword("and") {
+			break
+		}
+		p.next()
+	}
+
+	// Apply the terms directly, rather than as a single predicate, so that each one that fails is
+	// reported separately.
+	return func(q *interfaceQuery) error {
+		for _, t := range terms {
+			if err := t(q); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// parseUnary parses: "not" unary | "(" or ")" | term
+func (p *parser) parseUnary() (QueryParam, error) {
+	switch {
+	case p.isKeyword("not"):
+		p.next()
+		q, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(q), nil
+
+	case p.peek().kind == tokenLParen:
+		p.next()
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenRParen {
+			return nil, p.unexpected("\")\"")
+		}
+		p.next()
+		return q, nil
+
+	default:
+		return p.parseTerm()
+	}
+}
+
+var flagTerms = map[string]QueryParam{
+	"up":           IsUp(),
+	"broadcast":    IsBroadcast(),
+	"loopback":     IsLoopback(),
+	"pointtopoint": IsPointToPoint(),
+	"multicast":    IsMulticast(),
+	"ipv4":         HasIPv4Address(),
+	"ipv6":         HasIPv6Address(),
+	"public-ipv4":  HasPublicIPv4Address(),
+	"public-ipv6":  HasPublicIPv6Address(),
+	"any":          func(*interfaceQuery) error { return nil },
+}
+
+func (p *parser) parseTerm() (QueryParam, error) {
+	t := p.peek()
+	if t.kind != tokenWord || t.text == "and" || t.text == "or" {
+		return nil, p.unexpected("a term")
+	}
+	if q, found := flagTerms[t.text]; found {
+		p.next()
+		return q, nil
+	}
+
+	// Name and address terms are wrapped with All, since repeating WithName etc. matches any of
+	// the values given rather than all of them.
+	switch t.text {
+	case "name":
+		p.next()
+		switch p.peek().kind {
+		case tokenEquals:
+			p.next()
+			v, err := p.parseValue()
+			return All(WithName(v)), err
+		case tokenTilde:
+			p.next()
+			v, err := p.parseValue()
+			return WithNameRegexp(v), err
+		}
+		switch {
+		case p.isKeyword("like"):
+			p.next()
+			v, err := p.parseValue()
+			return WithNamePattern(v), err
+		case p.isKeyword("prefix"):
+			p.next()
+			v, err := p.parseValue()
+			return WithNamePrefix(v), err
+		default:
+			return nil, p.unexpected("\"=\", \"~\", \"like\" or \"prefix\"")
+		}
+
+	case "mac":
+		p.next()
+		if p.isKeyword("prefix") {
+			p.next()
+			v, err := p.parseValue()
+			return WithMACPrefix(v), err
+		}
+		if p.peek().kind != tokenEquals {
+			return nil, p.unexpected("\"=\" or \"prefix\"")
+		}
+		p.next()
+		v, err := p.parseValue()
+		return All(WithMAC(v)), err
+
+	case "ip":
+		p.next()
+		v, err := p.parseEquals()
+		return All(WithIP(v)), err
+
+	case "cidr":
+		p.next()
+		v, err := p.parseValue()
+		return InSubnet(v), err
+
+	case "group":
+		p.next()
+		v, err := p.parseEquals()
+		return HasMulticastGroup(v), err
+
+	case "class":
+		p.next()
+		v, err := p.parseEquals()
+		if err != nil {
+			return nil, err
+		}
+		class, err := ParseAddressClass(v)
+		if err != nil {
+			return nil, err
+		}
+		return HasAddressOfClass(class), nil
+
+	case "index":
+		p.next()
+		v, err := p.parseEquals()
+		if err != nil {
+			return nil, err
+		}
+		index, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q at column %d", v, p.prev().pos+1)
+		}
+		return WithIndex(index), nil
+
+	case "mtu":
+		p.next()
+		op := p.peek()
+		if op.kind != tokenEquals && op.kind != tokenCompare {
+			return nil, p.unexpected("\"=\", \"<\", \"<=\", \">\" or \">=\"")
+		}
+		p.next()
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		// Bounding the MTU keeps "mtu > N" from overflowing, even where int is 32 bits.
+		mtu, err := strconv.Atoi(v)
+		if err != nil || mtu < 0 || mtu >= math.MaxInt32 {
+			return nil, fmt.Errorf("invalid MTU %q at column %d", v, p.prev().pos+1)
+		}
+		if op.text == "<" && mtu == 0 {
+			return nil, fmt.Errorf("no MTU is less than 0 at column %d", p.prev().pos+1)
+		}
+		switch op.text {
+		case "<":
+			return WithMTURange(0, mtu-1), nil
+		case "<=":
+			return WithMTURange(0, mtu), nil
+		case ">":
+			return WithMTURange(mtu+1, math.MaxInt), nil
+		case ">=":
+			return WithMTURange(mtu, math.MaxInt), nil
+		default:
+			return WithMTU(mtu), nil
+		}
+
+	case "kind":
+		p.next()
+		v, err := p.parseEquals()
+		return WithKind(InterfaceKind(v)), err
+
+	case "vlan":
+		p.next()
+		v, err := p.parseEquals()
+		if err != nil {
+			return nil, err
+		}
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VLAN ID %q at column %d", v, p.prev().pos+1)
+		}
+		return WithVLANID(id), nil
+
+	case "parent":
+		p.next()
+		v, err := p.parseEquals()
+		return WithParent(v), err
+
+	case "operstate":
+		p.next()
+		v, err := p.parseEquals()
+		return WithOperState(v), err
+
+	default:
+		return nil, fmt.Errorf("unknown term %s at column %d", t, t.pos+1)
+	}
+}
+
+// parseEquals parses: "=" value
+func (p *parser) parseEquals() (string, error) {
+	if p.peek().kind != tokenEquals {
+		return "", p.unexpected("\"=\"")
+	}
+	p.next()
+	return p.parseValue()
+}
+
+func (p *parser) parseValue() (string, error) {
+	t := p.peek()
+	if t.kind != tokenWord && t.kind != tokenString {
+		return "", p.unexpected("a value")
+	}
+	p.next()
+	return t.text, nil
+}
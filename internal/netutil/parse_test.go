@@ -0,0 +1,108 @@
+package netutil
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func names(ifs []net.Interface) []string {
+	var n []string
+	for _, ifi := range ifs {
+		n = append(n, ifi.Name)
+	}
+	return n
+}
+
+func TestParseQuery(t *testing.T) {
+	getAddrsForInterface = mockGetAddrsForInterface
+	getLinkInfo = mockGetLinkInfo
+	getMulticastAddrsForInterface = mockGetMulticastAddrsForInterface
+
+	for query, expected := range map[string][]string{
+		"any":                                    {"lo0", "en0", "en1", "utun0"},
+		"up":                                     {"lo0", "en1", "utun0"},
+		"up and not loopback":                    {"en1", "utun0"},
+		"not up or loopback":                     {"lo0", "en0"},
+		"not (up and multicast)":                 {"en0"},
+		`name = en0 or name = "utun0"`:           {"en0", "utun0"},
+		`name ~ "^(en|lo)[0-9]$" and not ipv4`:   {"en0", "en1"},
+		"up and (cidr 192.168.0.0/16 or ipv6)":   {"lo0", "en1", "utun0"},
+		"cidr 127.0.0.0/8 or public-ipv6":        {"lo0", "en1"},
+		"mac = 00:01:02:03:04:07 or ip = ::1":    {"lo0", "en1"},
+		"up and not (pointtopoint or loopback)":  {"en1"},
+		"broadcast and multicast and not ipv6\n": {"utun0"},
+		"name = en0 and name = en1":              nil,
+		`name like "en*" and up`:                 {"en1"},
+		"name prefix u or index = 0":             {"lo0", "utun0"},
+		"mac prefix 00:01:02:03:04 and not up":   {"en0"},
+		"mtu >= 1500 and mtu<9000":               {"en0"},
+		"mtu > 1500 or mtu <= 1380":              {"lo0", "en1", "utun0"},
+		"mtu = 1500":                             {"en0"},
+		"kind = vlan and vlan = 10":              {"en1"},
+		"parent = en0 or operstate = up":         {"en1", "utun0"},
+		"kind = physical or kind = tun":          {"en0", "utun0"},
+		"class = loopback or class = global":     {"lo0", "en1"},
+		"group = 239.255.255.250 and not ipv6":   {"utun0"},
+	} {
+		q, err := ParseQuery(query)
+		assert.Nil(t, err, query)
+		filtered, err := FilterInterfaces(testIfs, q)
+		assert.Nil(t, err, query)
+		assert.Equal(t, expected, names(filtered), query)
+	}
+}
+
+func TestParseQuery_Reasons(t *testing.T) {
+	getAddrsForInterface = mockGetAddrsForInterface
+	q, err := ParseQuery(`up and not loopback and (name ~ "^vlan" or cidr 10.20.0.0/16)`)
+	assert.Nil(t, err)
+
+	_, reasons, err := Explain(testIfs[0], q)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		`does not match not loopback`,
+		`does not match (name ~ "^vlan" or cidr 10.20.0.0/16)`,
+	}, reasons)
+}
+
+func TestParseQuery_Invalid(t *testing.T) {
+	for query, expected := range map[string]string{
+		"":                    "expected a term at column 1, found end of query",
+		"up and":              "expected a term at column 7, found end of query",
+		"up or or down":       `expected a term at column 7, found "or"`,
+		"(up and loopback":    `expected ")" at column 17, found end of query`,
+		"up loopback":         `expected "and", "or" or end of query at column 4, found "loopback"`,
+		"upp":                 `unknown term "upp" at column 1`,
+		"name eth0":           `expected "=", "~", "like" or "prefix" at column 6, found "eth0"`,
+		`name = "eth0`:        "unterminated string at column 8",
+		`name ~ "["`:          "invalid name pattern",
+		"cidr 10.0.0.0/33":    "invalid subnet",
+		"mac = 00:01:02":      "invalid hardware address",
+		"ip = (":              `expected a value at column 6, found "("`,
+		"up and (not)":        `expected a term at column 12, found ")"`,
+		"multicast ) and up":  `expected "and", "or" or end of query at column 11, found ")"`,
+		"name = eth0 and ~ x": `expected a term at column 17, found "~"`,
+		`name like "["`:       "invalid name pattern",
+		"mac prefix 0g":       "invalid hardware address prefix",
+		"mac ~ 00":            `expected "=" or "prefix" at column 5, found "~"`,
+		"index = eth0":        `invalid index "eth0" at column 9`,
+		"mtu ~ 1500":          `expected "=", "<", "<=", ">" or ">=" at column 5, found "~"`,
+		"mtu >= big":          `invalid MTU "big" at column 8`,
+		"mtu > -1":            `invalid MTU "-1" at column 7`,
+		"mtu > 2147483647":    `invalid MTU "2147483647" at column 7`,
+		"mtu < 0":             "no MTU is less than 0 at column 7",
+		"kind = vxlan":        "invalid interface kind",
+		"vlan = 0":            "invalid VLAN ID",
+		"vlan = ten":          `invalid VLAN ID "ten" at column 8`,
+		"parent eth0":         `expected "=" at column 8, found "eth0"`,
+		"operstate = on":      "invalid operational state",
+		"class = public":      "invalid address class",
+		"group = 10.0.0.1":    "invalid multicast group",
+	} {
+		_, err := ParseQuery(query)
+		assert.ErrorContains(t, err, expected, query)
+	}
+}
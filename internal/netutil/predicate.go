@@ -0,0 +1,282 @@
+package netutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// predicate is a condition that cannot be expressed by the fixed fields of interfaceQuery. desc
+// describes the condition in query language syntax (see ParseQuery).
+type predicate struct {
+	desc string
+	test func(iface net.Interface) (bool, error)
+}
+
+func withPredicate(desc string, test func(iface net.Interface) (bool, error)) QueryParam {
+	return func(q *interfaceQuery) error {
+		q.predicates = append(q.predicates, predicate{desc, test})
+		return nil
+	}
+}
+
+// subquery builds a query from params, for use inside a combinator.
+func subquery(params []QueryParam) (interfaceQuery, error) {
+	var q interfaceQuery
+	for _, p := range params {
+		if err := p(&q); err != nil {
+			return interfaceQuery{}, err
+		}
+	}
+	return q, nil
+}
+
+// All matches interfaces that match every one of params. It is only needed inside Any or Not, since
+// FilterInterfaces already requires every parameter it is given to match.
+func All(params ...QueryParam) QueryParam {
+	return func(q *interfaceQuery) error {
+		sq, err := subquery(params)
+		if err != nil {
+			return err
+		}
+		return withPredicate(group(sq.String()), func(iface net.Interface) (bool, error) {
+			return matches(sq, iface)
+		})(q)
+	}
+}
+
+// Any matches interfaces that match at least one of params, e.g.
+//
+//	Any(WithName("vlan10"), All(IsUp(), HasIPv6Address()))
+//
+// Use All to require several conditions in one alternative.
+func Any(params ...QueryParam) QueryParam {
+	return func(q *interfaceQuery) error {
+		var sqs []interfaceQuery
+		var descs []string
+		for _, p := range params {
+			sq, err := subquery([]QueryParam{p})
+			if err != nil {
+				return err
+			}
+			sqs = append(sqs, sq)
+			descs = append(descs, sq.String())
+		}
+		return withPredicate("("+strings.Join(descs, " or ")+")", func(iface net.Interface) (bool, error) {
+			// An alternative that cannot be evaluated does not prevent another from matching.
+			var errs []error
+			for _, sq := range sqs {
+				match, err := matches(sq, iface)
+				if match {
+					return true, nil
+				}
+				if err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return false, errors.Join(errs...)
+		})(q)
+	}
+}
+
+// Not matches interfaces that do not match all of params, so Not(IsUp(), IsLoopback()) matches
+// interfaces that are down or not loopback.
+func Not(params ...QueryParam) QueryParam {
+	return func(q *interfaceQuery) error {
+		sq, err := subquery(params)
+		if err != nil {
+			return err
+		}
+		return withPredicate("not "+group(sq.String()), func(iface net.Interface) (bool, error) {
+			match, err := matches(sq, iface)
+			return !match, err
+		})(q)
+	}
+}
+
+// group parenthesizes desc if it combines several terms.
+func group(desc string) string {
+	if strings.Contains(desc, " and ") || strings.Contains(desc, " or ") {
+		return "(" + desc + ")"
+	}
+	return desc
+}
+
+// WithNameRegexp matches interfaces whose name matches the regular expression re.
+func WithNameRegexp(re string) QueryParam {
+	return func(q *interfaceQuery) error {
+		r, err := regexp.Compile(re)
+		if err != nil {
+			return fmt.Errorf("invalid name pattern: %w", err)
+		}
+		return withPredicate("name ~ "+strconv.Quote(re), func(iface net.Interface) (bool, error) {
+			return r.MatchString(iface.Name), nil
+		})(q)
+	}
+}
+
+// InSubnet matches interfaces with an address in the CIDR block cidr, e.g. "10.20.0.0/16".
+func InSubnet(cidr string) QueryParam {
+	return func(q *interfaceQuery) error {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid subnet: %w", err)
+		}
+		return withPredicate("cidr "+subnet.String(), func(iface net.Interface) (bool, error) {
+			addrs, err := getAddrsForInterface(iface)
+			if err != nil {
+				return false, fmt.Errorf("listing addresses: %w", err)
+			}
+			for _, a := range addrs {
+				if ipNet, ok := a.(*net.IPNet); ok && subnet.Contains(ipNet.IP) {
+					return true, nil
+				}
+			}
+			return false, nil
+		})(q)
+	}
+}
+
+// WithNamePattern matches interfaces whose name matches the shell pattern pattern, e.g. "vlan*"
+// (see path.Match).
+func WithNamePattern(pattern string) QueryParam {
+	return func(q *interfaceQuery) error {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid name pattern: %q: %w", pattern, err)
+		}
+		return withPredicate("name like "+strconv.Quote(pattern), func(iface net.Interface) (bool, error) {
+			match, _ := path.Match(pattern, iface.Name)
+			return match, nil
+		})(q)
+	}
+}
+
+// WithNamePrefix matches interfaces whose name begins with prefix.
+func WithNamePrefix(prefix string) QueryParam {
+	return withPredicate("name prefix "+strconv.Quote(prefix), func(iface net.Interface) (bool, error) {
+		return strings.HasPrefix(iface.Name, prefix), nil
+	})
+}
+
+// WithMACPrefix matches interfaces whose hardware address begins with prefix, given as colon- or
+// hyphen-separated hexadecimal bytes, e.g. an OUI such as "00:1a:2b".
+func WithMACPrefix(prefix string) QueryParam {
+	return func(q *interfaceQuery) error {
+		p, err := parseMACPrefix(prefix)
+		if err != nil {
+			return err
+		}
+		return withPredicate("mac prefix "+p.String(), func(iface net.Interface) (bool, error) {
+			return bytes.HasPrefix(iface.HardwareAddr, p), nil
+		})(q)
+	}
+}
+
+func parseMACPrefix(s string) (net.HardwareAddr, error) {
+	var prefix net.HardwareAddr
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == '-' }) {
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil || len(part) != 2 {
+			return nil, fmt.Errorf("invalid hardware address prefix: %q", s)
+		}
+		prefix = append(prefix, byte(b))
+	}
+	if len(prefix) == 0 || len(prefix) > 8 {
+		return nil, fmt.Errorf("invalid hardware address prefix: %q", s)
+	}
+	return prefix, nil
+}
+
+// WithIndex matches the interface with the given index.
+func WithIndex(index int) QueryParam {
+	return withPredicate("index = "+strconv.Itoa(index), func(iface net.Interface) (bool, error) {
+		return iface.Index == index, nil
+	})
+}
+
+// String describes q in query language syntax.
+func (q interfaceQuery) String() string {
+	var terms []string
+	for _, f := range []struct {
+		desired *bool
+		name    string
+	}{
+		{q.isUp, "up"},
+		{q.isBroadcast, "broadcast"},
+		{q.isLoopback, "loopback"},
+		{q.isPointToPoint, "pointtopoint"},
+		{q.isMulticast, "multicast"},
+		{q.hasIPv4, "ipv4"},
+		{q.hasPublicIPv4, "public-ipv4"},
+		{q.hasIPv6, "ipv6"},
+		{q.hasPublicIPv6, "public-ipv6"},
+	} {
+		if f.desired != nil {
+			terms = append(terms, describeCondition(*f.desired, f.name, "not "+f.name))
+		}
+	}
+
+	var names, macs, ips []string
+	for _, n := range q.names {
+		names = append(names, "name = "+strconv.Quote(n))
+	}
+	for _, m := range q.macs {
+		macs = append(macs, "mac = "+m.String())
+	}
+	for _, ip := range q.ips {
+		ips = append(ips, "ip = "+ip)
+	}
+	for _, alternatives := range [][]string{names, macs, ips} {
+		switch len(alternatives) {
+		case 0:
+		case 1:
+			terms = append(terms, alternatives[0])
+		default:
+			terms = append(terms, "("+strings.Join(alternatives, " or ")+")")
+		}
+	}
+
+	for _, p := range q.predicates {
+		terms = append(terms, p.desc)
+	}
+
+	if len(terms) == 0 {
+		return "any"
+	}
+	return strings.Join(terms, " and ")
+}
+
+// HasMulticastGroup matches interfaces that have joined the multicast group ip, e.g.
+// "239.255.255.250".
+func HasMulticastGroup(ip string) QueryParam {
+	return func(q *interfaceQuery) error {
+		group := net.ParseIP(ip)
+		if group == nil || !group.IsMulticast() {
+			return fmt.Errorf("invalid multicast group: %q", ip)
+		}
+		return withPredicate("group = "+group.String(), func(iface net.Interface) (bool, error) {
+			addrs, err := getMulticastAddrsForInterface(iface)
+			if err != nil {
+				return false, fmt.Errorf("listing multicast addresses: %w", err)
+			}
+			for _, a := range addrs {
+				switch t := a.(type) {
+				case *net.IPAddr:
+					if t.IP.Equal(group) {
+						return true, nil
+					}
+				case *net.IPNet:
+					if t.IP.Equal(group) {
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		})(q)
+	}
+}
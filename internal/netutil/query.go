@@ -1,8 +1,10 @@
 package netutil
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"strings"
 )
 
 type QueryParam func(q *interfaceQuery) error
@@ -20,18 +22,86 @@ func FilterInterfaces(ifs []net.Interface, params ...QueryParam) ([]net.Interfac
 	}
 
 	matchingIfs := make([]net.Interface, 0)
+	var errs []error
 	for _, iface := range ifs {
-		match, err := matches(q, iface)
+		reasons, err := mismatches(q, iface)
 		if err != nil {
-			return nil, err
-		} else if match {
+			err = fmt.Errorf("inspecting interface %s: %w", iface.Name, err)
+			switch q.onError {
+			case failOnError:
+				errs = append(errs, err)
+			case includeOnError:
+				matchingIfs = append(matchingIfs, iface)
+			}
+		} else if len(reasons) == 0 {
 			matchingIfs = append(matchingIfs, iface)
 		}
 	}
 
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("filtering interfaces: %w", errors.Join(errs...))
+	}
 	return matchingIfs, nil
 }
 
+// Explain reports whether iface matches the query described by params and, if it does not, every
+// reason it fails to.
+func Explain(iface net.Interface, params ...QueryParam) (bool, []string, error) {
+	var q interfaceQuery
+	for _, p := range params {
+		if err := p(&q); err != nil {
+			return false, nil, fmt.Errorf("explaining interface: %w", err)
+		}
+	}
+
+	reasons, err := mismatches(q, iface)
+	if err != nil {
+		switch q.onError {
+		case failOnError:
+			return false, nil, fmt.Errorf("inspecting interface %s: %w", iface.Name, err)
+		case includeOnError:
+			return true, nil, nil
+		default:
+			return false, append(reasons, "could not be inspected: "+err.Error()), nil
+		}
+	}
+	return len(reasons) == 0, reasons, nil
+}
+
+type errorPolicy int
+
+const (
+	skipOnError errorPolicy = iota
+	failOnError
+	includeOnError
+)
+
+// FailOnError makes FilterInterfaces and GetInterfaces fail, listing every interface that could not
+// be inspected, if the addresses or link information of any interface cannot be read. Like the
+// other error policies, it only applies to the top level of a query, not inside Any, All or Not.
+func FailOnError() QueryParam {
+	return func(q *interfaceQuery) error {
+		q.onError = failOnError
+		return nil
+	}
+}
+
+// SkipOnError excludes interfaces that cannot be inspected. This is the default.
+func SkipOnError() QueryParam {
+	return func(q *interfaceQuery) error {
+		q.onError = skipOnError
+		return nil
+	}
+}
+
+// IncludeOnError includes interfaces that cannot be inspected, whatever the rest of the query says.
+func IncludeOnError() QueryParam {
+	return func(q *interfaceQuery) error {
+		q.onError = includeOnError
+		return nil
+	}
+}
+
 func WithName(name string) QueryParam {
 	return func(q *interfaceQuery) error {
 		q.names = append(q.names, name)
@@ -220,87 +290,123 @@ type interfaceQuery struct {
 	hasPublicIPv4  *bool
 	hasIPv6        *bool
 	hasPublicIPv6  *bool
-	FailOnError    bool
+	predicates     []predicate
+	onError        errorPolicy
 }
 
 func matches(q interfaceQuery, iface net.Interface) (bool, error) {
-	if !flagMatches(q.isUp, iface, net.FlagUp) {
-		return false, nil
-	}
-	if !flagMatches(q.isBroadcast, iface, net.FlagBroadcast) {
-		return false, nil
-	}
-	if !flagMatches(q.isLoopback, iface, net.FlagLoopback) {
-		return false, nil
-	}
-	if !flagMatches(q.isPointToPoint, iface, net.FlagPointToPoint) {
-		return false, nil
-	}
-	if !flagMatches(q.isMulticast, iface, net.FlagMulticast) {
-		return false, nil
+	reasons, err := mismatches(q, iface)
+	return err == nil && len(reasons) == 0, err
+}
+
+// mismatches returns the reasons iface does not match q. If some of the information needed cannot
+// be read, it also returns an error, and the reasons are those that could be determined.
+func mismatches(q interfaceQuery, iface net.Interface) ([]string, error) {
+	var reasons []string
+	var errs []error
+	for _, f := range []struct {
+		desired *bool
+		flag    net.Flags
+	}{
+		{q.isUp, net.FlagUp},
+		{q.isBroadcast, net.FlagBroadcast},
+		{q.isLoopback, net.FlagLoopback},
+		{q.isPointToPoint, net.FlagPointToPoint},
+		{q.isMulticast, net.FlagMulticast},
+	} {
+		if !flagMatches(f.desired, iface, f.flag) {
+			reasons = append(reasons, describeCondition(*f.desired, "is not "+f.flag.String(), "is "+f.flag.String()))
+		}
 	}
 
 	if len(q.names) > 0 {
 		if !stringArrayContains(q.names, iface.Name) {
-			return false, nil
+			reasons = append(reasons, "name is not one of "+strings.Join(q.names, ", "))
 		}
 	}
 
 	if len(q.macs) > 0 {
 		if !bytesArrayContains(q.macs, iface.HardwareAddr) {
-			return false, nil
+			var macs []string
+			for _, m := range q.macs {
+				macs = append(macs, m.String())
+			}
+			reasons = append(reasons, "hardware address is not one of "+strings.Join(macs, ", "))
+		}
+	}
+
+	if len(q.ips) > 0 || q.hasIPv4 != nil || q.hasPublicIPv4 != nil || q.hasIPv6 != nil || q.hasPublicIPv6 != nil {
+		addrs, err := getAddrsForInterface(iface)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing addresses: %w", err))
+		} else {
+			reasons = append(reasons, addressMismatches(q, addrs)...)
+		}
+	}
+
+	for _, p := range q.predicates {
+		match, err := p.test(iface)
+		if err != nil {
+			errs = append(errs, err)
+		} else if !match {
+			reasons = append(reasons, "does not match "+p.desc)
 		}
 	}
 
+	return reasons, errors.Join(errs...)
+}
+
+func addressMismatches(q interfaceQuery, addrs []net.Addr) []string {
 	matchedIP := false
 	hasIPv4 := false
 	hasPublicIPv4 := false
 	hasIPv6 := false
 	hasPublicIPv6 := false
-	if len(q.ips) > 0 || q.hasIPv4 != nil || q.hasPublicIPv4 != nil || q.hasIPv6 != nil || q.hasPublicIPv6 != nil {
-		addrs, err := getAddrsForInterface(iface)
-		if err != nil && q.FailOnError {
-			return false, fmt.Errorf("filtering interfaces: %w", err)
-		}
-
-		for _, a := range addrs {
-			switch t := a.(type) {
-			case *net.IPNet:
-				if stringArrayContains(q.ips, t.IP.String()) {
-					matchedIP = true
+	for _, a := range addrs {
+		switch t := a.(type) {
+		case *net.IPNet:
+			if stringArrayContains(q.ips, t.IP.String()) {
+				matchedIP = true
+			}
+			if t.IP.To4() != nil {
+				hasIPv4 = true
+				if !isPrivateIPv4(t.IP) {
+					hasPublicIPv4 = true
 				}
-				if t.IP.To4() != nil {
-					hasIPv4 = true
-					if !isPrivateIPv4(t.IP) {
-						hasPublicIPv4 = true
-					}
-				} else if t.IP.To16() != nil {
-					hasIPv6 = true
-					if !isPrivateIPv6(t.IP) {
-						hasPublicIPv6 = true
-					}
+			} else if t.IP.To16() != nil {
+				hasIPv6 = true
+				if !isPrivateIPv6(t.IP) {
+					hasPublicIPv6 = true
 				}
 			}
 		}
 	}
 
+	var reasons []string
 	if len(q.ips) > 0 && !matchedIP {
-		return false, nil
+		reasons = append(reasons, "has none of the addresses "+strings.Join(q.ips, ", "))
 	}
 	if q.hasIPv4 != nil && hasIPv4 != *q.hasIPv4 {
-		return false, nil
+		reasons = append(reasons, describeCondition(*q.hasIPv4, "has no IPv4 address", "has an IPv4 address"))
 	}
 	if q.hasPublicIPv4 != nil && hasPublicIPv4 != *q.hasPublicIPv4 {
-		return false, nil
+		reasons = append(reasons, describeCondition(*q.hasPublicIPv4, "has no public IPv4 address", "has a public IPv4 address"))
 	}
 	if q.hasIPv6 != nil && hasIPv6 != *q.hasIPv6 {
-		return false, nil
+		reasons = append(reasons, describeCondition(*q.hasIPv6, "has no IPv6 address", "has an IPv6 address"))
 	}
 	if q.hasPublicIPv6 != nil && hasPublicIPv6 != *q.hasPublicIPv6 {
-		return false, nil
+		reasons = append(reasons, describeCondition(*q.hasPublicIPv6, "has no public IPv6 address", "has a public IPv6 address"))
 	}
+	return reasons
+}
 
-	return true, nil
+// describeCondition returns the reason a condition that should have been desired was not met.
+func describeCondition(desired bool, whenWanted, whenUnwanted string) string {
+	if desired {
+		return whenWanted
+	}
+	return whenUnwanted
 }
 
 func flagMatches(desired *bool, iface net.Interface, flag net.Flags) bool {
@@ -310,3 +416,7 @@ func flagMatches(desired *bool, iface net.Interface, flag net.Flags) bool {
 var getAddrsForInterface = func(iface net.Interface) ([]net.Addr, error) {
 	return iface.Addrs()
 }
+
+var getMulticastAddrsForInterface = func(iface net.Interface) ([]net.Addr, error) {
+	return iface.MulticastAddrs()
+}
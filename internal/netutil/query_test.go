@@ -1,6 +1,7 @@
 package netutil
 
 import (
+	"errors"
 	"net"
 	"testing"
 
@@ -137,28 +138,225 @@ func TestFilterInterfaces_WithNames(t *testing.T) {
 	assert.Len(t, filtered, 2)
 }
 
+func TestFilterInterfaces_InSubnet(t *testing.T) {
+	getAddrsForInterface = mockGetAddrsForInterface
+	filtered, _ := FilterInterfaces(testIfs, InSubnet("192.168.0.0/16"))
+	assert.Equal(t, []string{"utun0"}, names(filtered))
+
+	_, err := FilterInterfaces(testIfs, InSubnet("192.168.0.0"))
+	assert.ErrorContains(t, err, "invalid subnet")
+}
+
+func TestFilterInterfaces_WithNamePattern(t *testing.T) {
+	filtered, _ := FilterInterfaces(testIfs, WithNamePattern("en[0-9]"))
+	assert.Equal(t, []string{"en0", "en1"}, names(filtered))
+
+	_, err := FilterInterfaces(testIfs, WithNamePattern("en["))
+	assert.ErrorContains(t, err, "invalid name pattern")
+}
+
+func TestFilterInterfaces_WithNameRegexp(t *testing.T) {
+	filtered, _ := FilterInterfaces(testIfs, WithNameRegexp("^(lo|utun)"))
+	assert.Equal(t, []string{"lo0", "utun0"}, names(filtered))
+}
+
+func TestFilterInterfaces_WithNamePrefix(t *testing.T) {
+	filtered, _ := FilterInterfaces(testIfs, WithNamePrefix("en"))
+	assert.Equal(t, []string{"en0", "en1"}, names(filtered))
+}
+
+func TestFilterInterfaces_WithMACPrefix(t *testing.T) {
+	filtered, _ := FilterInterfaces(testIfs, WithMACPrefix("00-01-02"))
+	assert.Len(t, filtered, 4)
+
+	filtered, _ = FilterInterfaces(testIfs, WithMACPrefix("00:01:02:03:04:07"))
+	assert.Equal(t, []string{"en1"}, names(filtered))
+
+	for _, prefix := range []string{"", "0:1:2", "00:01:zz", "0001"} {
+		_, err := FilterInterfaces(testIfs, WithMACPrefix(prefix))
+		assert.ErrorContains(t, err, "invalid hardware address prefix", prefix)
+	}
+}
+
+func TestFilterInterfaces_WithIndex(t *testing.T) {
+	filtered, _ := FilterInterfaces(testIfs, WithIndex(2))
+	assert.Equal(t, []string{"en1"}, names(filtered))
+}
+
+func TestFilterInterfaces_WithMTURange(t *testing.T) {
+	filtered, _ := FilterInterfaces(testIfs, WithMTURange(1400, 9000))
+	assert.Equal(t, []string{"en0", "en1"}, names(filtered))
+
+	filtered, _ = FilterInterfaces(testIfs, WithMTU(1380))
+	assert.Equal(t, []string{"utun0"}, names(filtered))
+}
+
+func TestFilterInterfaces_WithKind(t *testing.T) {
+	getLinkInfo = mockGetLinkInfo
+	filtered, _ := FilterInterfaces(testIfs, WithKind(KindVLAN))
+	assert.Equal(t, []string{"en1"}, names(filtered))
+
+	_, err := FilterInterfaces(testIfs, WithKind("vxlan"))
+	assert.ErrorContains(t, err, "invalid interface kind")
+}
+
+func TestFilterInterfaces_WithVLANID(t *testing.T) {
+	getLinkInfo = mockGetLinkInfo
+	filtered, _ := FilterInterfaces(testIfs, WithVLANID(10))
+	assert.Equal(t, []string{"en1"}, names(filtered))
+
+	_, err := FilterInterfaces(testIfs, WithVLANID(4095))
+	assert.ErrorContains(t, err, "invalid VLAN ID")
+}
+
+func TestFilterInterfaces_WithVLANIDUnknown(t *testing.T) {
+	getLinkInfo = func(iface net.Interface) (LinkInfo, error) {
+		if iface.Index == 2 {
+			return LinkInfo{Kind: KindVLAN, Parent: "en0", OperState: "up"}, nil
+		}
+		return mockGetLinkInfo(iface)
+	}
+	defer func() { getLinkInfo = mockGetLinkInfo }()
+
+	filtered, err := FilterInterfaces(testIfs, WithVLANID(10), FailOnError())
+	assert.Empty(t, filtered)
+	assert.ErrorContains(t, err, "en1: VLAN ID is not known")
+}
+
+func TestFilterInterfaces_WithParent(t *testing.T) {
+	getLinkInfo = mockGetLinkInfo
+	filtered, _ := FilterInterfaces(testIfs, WithParent("en0"))
+	assert.Equal(t, []string{"en1"}, names(filtered))
+}
+
+func TestFilterInterfaces_WithOperState(t *testing.T) {
+	getLinkInfo = mockGetLinkInfo
+	filtered, _ := FilterInterfaces(testIfs, WithOperState("up"))
+	assert.Equal(t, []string{"utun0"}, names(filtered))
+
+	_, err := FilterInterfaces(testIfs, WithOperState("sideways"))
+	assert.ErrorContains(t, err, "invalid operational state")
+}
+
+var failingGetAddrsForInterface = func(iface net.Interface) ([]net.Addr, error) {
+	if iface.Index%2 == 1 {
+		return nil, errors.New("no such device")
+	}
+	return mockGetAddrsForInterface(iface)
+}
+
+func TestFilterInterfaces_SkipOnError(t *testing.T) {
+	getAddrsForInterface = failingGetAddrsForInterface
+
+	filtered, err := FilterInterfaces(testIfs, HasNoIPv4Address())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"en1"}, names(filtered))
+
+	filtered, err = FilterInterfaces(testIfs, SkipOnError(), InSubnet("127.0.0.0/8"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"lo0"}, names(filtered))
+}
+
+func TestFilterInterfaces_IncludeOnError(t *testing.T) {
+	getAddrsForInterface = failingGetAddrsForInterface
+
+	filtered, err := FilterInterfaces(testIfs, IncludeOnError(), HasIPv4Address())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"lo0", "en0", "utun0"}, names(filtered))
+}
+
+func TestFilterInterfaces_FailOnError(t *testing.T) {
+	getAddrsForInterface = failingGetAddrsForInterface
+
+	filtered, err := FilterInterfaces(testIfs, FailOnError(), IsUp(), Any(HasIPv6Address(), IsLoopback()))
+	assert.Nil(t, filtered)
+	assert.EqualError(t, err, "filtering interfaces: "+
+		"inspecting interface en0: listing addresses: no such device\n"+
+		"inspecting interface utun0: listing addresses: no such device")
+
+	filtered, err = FilterInterfaces(testIfs, FailOnError(), IsUp())
+	assert.Nil(t, err)
+	assert.Len(t, filtered, 3)
+}
+
+func TestFilterInterfaces_AnyWithError(t *testing.T) {
+	getAddrsForInterface = failingGetAddrsForInterface
+
+	filtered, err := FilterInterfaces(testIfs, FailOnError(), Any(HasIPv4Address(), WithNames("en0", "utun0")))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"lo0", "en0", "utun0"}, names(filtered))
+
+	filtered, err = FilterInterfaces(testIfs, FailOnError(), Any(HasIPv4Address(), WithName("en0")))
+	assert.Nil(t, filtered)
+	assert.EqualError(t, err, "filtering interfaces: inspecting interface utun0: listing addresses: no such device")
+}
+
+func TestExplain_Errors(t *testing.T) {
+	getAddrsForInterface = failingGetAddrsForInterface
+
+	match, reasons, err := Explain(testIfs[1], IsUp(), HasIPv4Address())
+	assert.Nil(t, err)
+	assert.False(t, match)
+	assert.Equal(t, []string{"is not up", "could not be inspected: listing addresses: no such device"}, reasons)
+
+	match, _, err = Explain(testIfs[1], IncludeOnError(), HasIPv4Address())
+	assert.Nil(t, err)
+	assert.True(t, match)
+
+	_, _, err = Explain(testIfs[1], FailOnError(), HasIPv4Address())
+	assert.EqualError(t, err, "inspecting interface en0: listing addresses: no such device")
+}
+
+var mockGetMulticastAddrsForInterface = func(iface net.Interface) ([]net.Addr, error) {
+	return [][]net.Addr{
+		{&net.IPAddr{IP: net.ParseIP("224.0.0.1")}, &net.IPAddr{IP: net.ParseIP("ff02::1")}},
+		{},
+		{&net.IPAddr{IP: net.ParseIP("239.255.255.250")}, &net.IPAddr{IP: net.ParseIP("ff02::c")}},
+		{&net.IPAddr{IP: net.ParseIP("239.255.255.250")}},
+	}[iface.Index], nil
+}
+
+func TestFilterInterfaces_HasMulticastGroup(t *testing.T) {
+	getMulticastAddrsForInterface = mockGetMulticastAddrsForInterface
+
+	filtered, _ := FilterInterfaces(testIfs, HasMulticastGroup("239.255.255.250"))
+	assert.Equal(t, []string{"en1", "utun0"}, names(filtered))
+
+	filtered, _ = FilterInterfaces(testIfs, HasMulticastGroup("ff02::c"))
+	assert.Equal(t, []string{"en1"}, names(filtered))
+
+	for _, ip := range []string{"10.0.0.1", "bogus"} {
+		_, err := FilterInterfaces(testIfs, HasMulticastGroup(ip))
+		assert.ErrorContains(t, err, "invalid multicast group", ip)
+	}
+}
+
 var testIfs = []net.Interface{
 	{
 		Index:        0,
 		Name:         "lo0",
+		MTU:          16384,
 		HardwareAddr: net.HardwareAddr{0x00, 0x01, 0x02, 0x03, 0x04, 0x05},
 		Flags:        net.FlagUp | net.FlagLoopback | net.FlagMulticast,
 	},
 	{
 		Index:        1,
 		Name:         "en0",
+		MTU:          1500,
 		HardwareAddr: net.HardwareAddr{0x00, 0x01, 0x02, 0x03, 0x04, 0x06},
 		Flags:        0,
 	},
 	{
 		Index:        2,
 		Name:         "en1",
+		MTU:          9000,
 		HardwareAddr: net.HardwareAddr{0x00, 0x01, 0x02, 0x03, 0x04, 0x07},
 		Flags:        net.FlagUp | net.FlagBroadcast | net.FlagMulticast,
 	},
 	{
 		Index:        3,
 		Name:         "utun0",
+		MTU:          1380,
 		HardwareAddr: net.HardwareAddr{0x00, 0x01, 0x02, 0x03, 0x04, 0x08},
 		Flags:        net.FlagUp | net.FlagBroadcast | net.FlagPointToPoint | net.FlagMulticast,
 	},
@@ -174,6 +372,75 @@ var mockGetAddrsForInterface = func(iface net.Interface) ([]net.Addr, error) {
 	return addrs[iface.Index], nil
 }
 
+var mockGetLinkInfo = func(iface net.Interface) (LinkInfo, error) {
+	return []LinkInfo{
+		{Kind: KindOther, OperState: "unknown"},
+		{Kind: KindPhysical, OperState: "down"},
+		{Kind: KindVLAN, VLANID: 10, Parent: "en0", OperState: "lowerlayerdown"},
+		{Kind: KindTun, OperState: "up"},
+	}[iface.Index], nil
+}
+
 func mockIPAddr(ip string) *net.IPNet {
 	return &net.IPNet{IP: net.ParseIP(ip)}
 }
+
+func TestExplain(t *testing.T) {
+	getAddrsForInterface = mockGetAddrsForInterface
+
+	match, reasons, err := Explain(testIfs[0], IsNotLoopback(), IsUp(), HasIPv4Address(), HasNoPublicIPv4Address())
+	assert.Nil(t, err)
+	assert.False(t, match)
+	assert.Equal(t, []string{"is loopback"}, reasons)
+
+	match, reasons, err = Explain(testIfs[1], IsUp(), HasIPv4Address(), WithNames("en1", "en2"))
+	assert.Nil(t, err)
+	assert.False(t, match)
+	assert.Equal(t, []string{"is not up", "name is not one of en1, en2", "has no IPv4 address"}, reasons)
+
+	match, reasons, err = Explain(testIfs[2], IsUp(), IsMulticast())
+	assert.Nil(t, err)
+	assert.True(t, match)
+	assert.Empty(t, reasons)
+}
+
+func TestFilterInterfaces_Any(t *testing.T) {
+	getAddrsForInterface = mockGetAddrsForInterface
+	filtered, _ := FilterInterfaces(testIfs, Any(WithName("en0"), All(IsUp(), HasIPv6Address())))
+	assert.Equal(t, []string{"lo0", "en0", "en1"}, names(filtered))
+}
+
+func TestFilterInterfaces_All(t *testing.T) {
+	filtered, _ := FilterInterfaces(testIfs, All(IsUp(), IsBroadcast()), All())
+	assert.Equal(t, []string{"en1", "utun0"}, names(filtered))
+}
+
+func TestFilterInterfaces_Not(t *testing.T) {
+	filtered, _ := FilterInterfaces(testIfs, Not(IsUp(), IsLoopback()))
+	assert.Equal(t, []string{"en0", "en1", "utun0"}, names(filtered))
+
+	filtered, _ = FilterInterfaces(testIfs, Not(WithNames("lo0", "en0")), IsUp())
+	assert.Equal(t, []string{"en1", "utun0"}, names(filtered))
+}
+
+func TestFilterInterfaces_NestedCombinators(t *testing.T) {
+	getAddrsForInterface = mockGetAddrsForInterface
+	filtered, _ := FilterInterfaces(testIfs, Not(Any(IsLoopback(), Not(HasIPv4Address()))))
+	assert.Equal(t, []string{"utun0"}, names(filtered))
+}
+
+func TestFilterInterfaces_CombinatorError(t *testing.T) {
+	_, err := FilterInterfaces(testIfs, Any(IsUp(), WithMAC("bogus")))
+	assert.NotNil(t, err)
+}
+
+func TestExplain_Combinators(t *testing.T) {
+	_, reasons, err := Explain(testIfs[1], Any(WithName("en1"), All(IsUp(), IsMulticast())), Not(IsLoopback()))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`does not match (name = "en1" or (up and multicast))`}, reasons)
+}
+
+func TestWithMAC_Invalid(t *testing.T) {
+	_, err := FilterInterfaces(testIfs, WithMAC("bogus"))
+	assert.ErrorContains(t, err, `invalid hardware address ("bogus")`)
+}
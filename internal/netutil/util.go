@@ -6,44 +6,25 @@ import (
 	"net"
 )
 
-var privateIPv4CIDRs []*net.IPNet
-var privateIPv6CIDRs []*net.IPNet
-
-func init() {
-	for _, s := range []string{"10.0.0.0/8", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.168.0.0/16"} {
-		_, c, err := net.ParseCIDR(s)
-		if err != nil {
-			panic(err)
-		}
-		privateIPv4CIDRs = append(privateIPv4CIDRs, c)
-	}
-
-	for _, s := range []string{"::1/128", "fe80::/10", "fc00::/7"} {
-		_, c, err := net.ParseCIDR(s)
-		if err != nil {
-			panic(err)
-		}
-		privateIPv6CIDRs = append(privateIPv6CIDRs, c)
-	}
-}
-
+// isPrivateIPv4 reports whether i is a loopback, link-local or RFC 1918 IPv4 address. Other
+// special-purpose addresses, notably CGNAT (100.64.0.0/10), count as public: they are not
+// necessarily reachable from the internet, but they are not on the local site either.
 func isPrivateIPv4(i net.IP) bool {
-	v4 := i.To4()
-	if v4 != nil {
-		for _, c := range privateIPv4CIDRs {
-			if c.Contains(i) {
-				return true
-			}
-		}
+	if i.To4() == nil {
+		return false
+	}
+	switch ClassifyAddress(i) {
+	case ClassLoopback, ClassLinkLocal, ClassPrivate:
+		return true
 	}
 	return false
 }
 
+// isPrivateIPv6 reports whether i is a loopback, link-local or unique local IPv6 address.
 func isPrivateIPv6(i net.IP) bool {
-	for _, c := range privateIPv6CIDRs {
-		if c.Contains(i) {
-			return true
-		}
+	switch ClassifyAddress(i) {
+	case ClassLoopback, ClassLinkLocal, ClassULA:
+		return true
 	}
 	return false
 }
@@ -53,7 +34,7 @@ func parseMacs(macs []string) ([]net.HardwareAddr, error) {
 	for _, s := range macs {
 		mac, err := net.ParseMAC(s)
 		if err != nil {
-			return nil, fmt.Errorf("listing interfaces: invalid hardware address (\"%s\") in query: %w", mac, err)
+			return nil, fmt.Errorf("listing interfaces: invalid hardware address (\"%s\") in query: %w", s, err)
 		}
 		parsedMacs = append(parsedMacs, mac)
 	}
@@ -81,3 +62,13 @@ func bytesArrayContains(a []net.HardwareAddr, b net.HardwareAddr) bool {
 	}
 	return false
 }
+
+// IsPrivateIP reports whether ip is a loopback, link-local or private (RFC 1918 or unique local)
+// address. CGNAT and other special-purpose addresses are not private; use ClassifyAddress to tell
+// them apart.
+func IsPrivateIP(ip net.IP) bool {
+	if ip.To4() != nil {
+		return isPrivateIPv4(ip)
+	}
+	return isPrivateIPv6(ip)
+}
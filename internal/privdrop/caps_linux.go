@@ -0,0 +1,47 @@
+package privdrop
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// dropCapabilities clears the permitted, effective and inheritable capability sets and prevents
+// the process from regaining privileges through execve. Capabilities are per-thread on Linux, so
+// the system calls are applied to every thread of the process.
+func dropCapabilities() error {
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+	_, _, errno := syscall.AllThreadsSyscall(unix.SYS_CAPSET,
+		uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0)
+	if errors.Is(errno, syscall.ENOTSUP) {
+		// AllThreadsSyscall is unavailable when cgo is enabled. Switching away from root clears
+		// every thread's capabilities anyway, so only fail if some are left.
+		return checkNoCapabilities()
+	} else if errno != 0 {
+		return errno
+	}
+
+	_, _, errno = syscall.AllThreadsSyscall6(unix.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func checkNoCapabilities() error {
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return err
+	}
+	for _, d := range data {
+		if d.Permitted != 0 || d.Effective != 0 {
+			return errors.New("capabilities cannot be dropped from all threads when built with cgo")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package privdrop
+
+func dropCapabilities() error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package privdrop
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// Drop switches to the given user and group, if either is specified, and then drops any
+// capabilities the process still holds. Capabilities are dropped last because changing IDs
+// requires CAP_SETUID and CAP_SETGID. Failing to drop capabilities is logged rather than returned,
+// since the process may legitimately keep them when allowed to run as root.
+func Drop(userName, groupName string) error {
+	if userName != "" || groupName != "" {
+		if err := SwitchUser(userName, groupName); err != nil {
+			return err
+		}
+	}
+	if err := DropCapabilities(); err != nil {
+		slog.Warn("error dropping privileges", "error", err)
+	}
+	return nil
+}
+
+// SwitchUser changes the process's user and group. If groupName is empty, the user's primary
+// group is used; if userName is empty, only the group is changed.
+func SwitchUser(userName, groupName string) error {
+	uid, gid, err := lookupIDs(userName, groupName)
+	if err != nil {
+		return err
+	}
+	if err := setIDs(uid, gid); err != nil {
+		return fmt.Errorf("switching to uid %d, gid %d: %w", uid, gid, err)
+	}
+	return nil
+}
+
+// DropCapabilities drops any capabilities (e.g. CAP_NET_RAW) the process still holds. It does
+// nothing on platforms without capabilities.
+func DropCapabilities() error {
+	if err := dropAllCapabilities(); err != nil {
+		return fmt.Errorf("dropping capabilities: %w", err)
+	}
+	return nil
+}
+
+// dropAllCapabilities is replaced in tests.
+var dropAllCapabilities = dropCapabilities
+
+// IsRoot reports whether the process is running with superuser privileges.
+func IsRoot() bool {
+	return os.Geteuid() == 0
+}
+
+func lookupIDs(userName, groupName string) (int, int, error) {
+	uid, gid := os.Getuid(), os.Getgid()
+
+	if userName != "" {
+		u, err := user.Lookup(userName)
+		if err != nil {
+			return 0, 0, fmt.Errorf("looking up user: %w", err)
+		}
+		uid, err = strconv.Atoi(u.Uid)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unsupported uid for %s: %q", userName, u.Uid)
+		}
+		gid, err = strconv.Atoi(u.Gid)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unsupported gid for %s: %q", userName, u.Gid)
+		}
+	}
+
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			return 0, 0, fmt.Errorf("looking up group: %w", err)
+		}
+		gid, err = strconv.Atoi(g.Gid)
+		if err != nil {
+			return 0, 0, fmt.Errorf("unsupported gid for %s: %q", groupName, g.Gid)
+		}
+	}
+
+	return uid, gid, nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package privdrop
+
+import (
+	"errors"
+	"runtime"
+)
+
+func setIDs(_, _ int) error {
+	return errors.New("changing user is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,26 @@
+package privdrop
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupIDs_Unchanged(t *testing.T) {
+	uid, gid, err := lookupIDs("", "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, os.Getuid(), uid)
+	assert.Equal(t, os.Getgid(), gid)
+}
+
+func TestLookupIDs_UnknownUser(t *testing.T) {
+	_, _, err := lookupIDs("no-such-user-for-forward-ssdp", "")
+	assert.NotNil(t, err)
+}
+
+func TestLookupIDs_UnknownGroup(t *testing.T) {
+	_, _, err := lookupIDs("", "no-such-group-for-forward-ssdp")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package privdrop
+
+import "syscall"
+
+// setIDs clears supplementary groups and sets the real and effective group and user IDs. The group
+// must be changed first, while the process is still privileged.
+func setIDs(uid, gid int) error {
+	if err := setgroups([]int{}); err != nil {
+		return err
+	}
+	if err := setgid(gid); err != nil {
+		return err
+	}
+	return setuid(uid)
+}
+
+// System calls, replaced in tests.
+var (
+	setgroups = syscall.Setgroups
+	setgid    = syscall.Setgid
+	setuid    = syscall.Setuid
+)
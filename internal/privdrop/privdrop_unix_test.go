@@ -0,0 +1,85 @@
+//go:build unix
+
+package privdrop
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordCalls replaces the system calls used to drop privileges with stubs that record the order
+// in which they are made. fail names a call that should return an error.
+func recordCalls(t *testing.T, fail string) *[]string {
+	var calls []string
+	record := func(name string) error {
+		calls = append(calls, name)
+		if name == fail {
+			return errors.New(name + " failed")
+		}
+		return nil
+	}
+
+	origSetgroups, origSetgid, origSetuid, origDrop := setgroups, setgid, setuid, dropAllCapabilities
+	t.Cleanup(func() {
+		setgroups, setgid, setuid, dropAllCapabilities = origSetgroups, origSetgid, origSetuid, origDrop
+	})
+
+	setgroups = func([]int) error { return record("setgroups") }
+	setgid = func(gid int) error { return record("setgid " + strconv.Itoa(gid)) }
+	setuid = func(uid int) error { return record("setuid " + strconv.Itoa(uid)) }
+	dropAllCapabilities = func() error { return record("capset") }
+
+	return &calls
+}
+
+func currentGroup(t *testing.T) (string, string) {
+	gid := strconv.Itoa(os.Getgid())
+	g, err := user.LookupGroupId(gid)
+	if err != nil {
+		t.Skipf("looking up current group: %v", err)
+	}
+	return g.Name, gid
+}
+
+func TestDrop_Order(t *testing.T) {
+	calls := recordCalls(t, "")
+	group, gid := currentGroup(t)
+
+	err := Drop("", group)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"setgroups", "setgid " + gid, "setuid " + strconv.Itoa(os.Getuid()), "capset"}, *calls)
+}
+
+func TestDrop_NoUser(t *testing.T) {
+	calls := recordCalls(t, "")
+
+	err := Drop("", "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"capset"}, *calls)
+}
+
+func TestDrop_SetgidFails(t *testing.T) {
+	group, gid := currentGroup(t)
+	calls := recordCalls(t, "setgid "+gid)
+
+	err := Drop("", group)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"setgroups", "setgid " + gid}, *calls)
+}
+
+func TestDrop_CapabilitiesFail(t *testing.T) {
+	calls := recordCalls(t, "capset")
+
+	err := Drop("", "")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"capset"}, *calls)
+}
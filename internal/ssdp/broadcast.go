@@ -0,0 +1,139 @@
+package ssdp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"golang.org/x/net/ipv4"
+)
+
+// Broadcast returns a protocol that relays IPv4 limited broadcasts (to 255.255.255.255) received
+// on any of the given UDP ports. They are re-broadcast to the directed broadcast address of each
+// outgoing interface.
+func Broadcast(name string, ports ...int) Protocol {
+	return Protocol{Name: name, Ports: ports}
+}
+
+func broadcastGroup(port int) Group {
+	return Group{Addr: &net.UDPAddr{IP: net.IPv4bcast, Port: port}, HopLimit: 1}
+}
+
+func (g Group) isBroadcast() bool {
+	return g.Addr != nil && g.Addr.IP.Equal(net.IPv4bcast)
+}
+
+// BroadcastListener receives broadcasts to a UDP port on a set of interfaces. Unlike multicast
+// listeners, a single socket serves every interface, and the interface a packet arrived on is
+// determined from its control message.
+type BroadcastListener struct {
+	protocol   string
+	port       int
+	conn       net.PacketConn
+	pConn      *ipv4.PacketConn
+	interfaces map[int]net.Interface
+	broadcasts map[int][]net.IP
+	buf        []byte
+}
+
+func NewBroadcastListener(ifs []net.Interface, protocol string, port int) (BroadcastListener, error) {
+	l := BroadcastListener{
+		protocol:   protocol,
+		port:       port,
+		interfaces: make(map[int]net.Interface),
+		broadcasts: make(map[int][]net.IP),
+		buf:        make([]byte, 65535),
+	}
+	for _, ifi := range ifs {
+		bcasts, err := broadcastAddrs(ifi)
+		if err != nil {
+			return BroadcastListener{}, fmt.Errorf("listing broadcast addresses for %s: %w", ifi.Name, err)
+		}
+		l.interfaces[ifi.Index] = ifi
+		l.broadcasts[ifi.Index] = bcasts
+	}
+
+	var err error
+	l.conn, err = net.ListenPacket("udp4", fmt.Sprintf("0.0.0.0:%d", port))
+	if err != nil {
+		return BroadcastListener{}, err
+	}
+
+	l.pConn = ipv4.NewPacketConn(l.conn)
+	err = l.pConn.SetControlMessage(ipv4.FlagInterface|ipv4.FlagDst, true)
+	if err != nil {
+		_ = l.conn.Close()
+		return BroadcastListener{}, fmt.Errorf("requesting control messages: %w", err)
+	}
+
+	return l, nil
+}
+
+// Listen reads packets until the connection is closed or fails, then sends the error to errs and
+// calls wg.Done. Unicast packets and packets from other interfaces are ignored.
+func (l BroadcastListener) Listen(messages chan<- Message, errs chan<- error, wg *sync.WaitGroup) {
+	for {
+		n, cm, addr, err := l.pConn.ReadFrom(l.buf)
+		if err != nil {
+			errs <- err
+			wg.Done()
+			return
+		}
+		if cm == nil || !l.isBroadcast(cm.IfIndex, cm.Dst) {
+			continue
+		}
+
+		msg := make([]byte, n)
+		copy(msg, l.buf)
+		messages <- Message{
+			Protocol: l.protocol,
+			Group:    broadcastGroup(l.port),
+			Network:  "udp4",
+			IfName:   l.interfaces[cm.IfIndex].Name,
+			SourceIP: addr,
+			Data:     msg,
+		}
+	}
+}
+
+func (l BroadcastListener) Close() error {
+	return l.conn.Close()
+}
+
+func (l BroadcastListener) isBroadcast(ifIndex int, dst net.IP) bool {
+	if _, found := l.interfaces[ifIndex]; !found {
+		return false
+	}
+	if dst.Equal(net.IPv4bcast) {
+		return true
+	}
+	for _, b := range l.broadcasts[ifIndex] {
+		if dst.Equal(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// broadcastAddrs returns the directed broadcast address of each IPv4 network on ifi.
+func broadcastAddrs(ifi net.Interface) ([]net.IP, error) {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var bcasts []net.IP
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || len(ipNet.Mask) != net.IPv4len {
+			continue
+		}
+		ip := ipNet.IP.To4()
+		b := make(net.IP, net.IPv4len)
+		for i := range b {
+			b[i] = ip[i] | ^ipNet.Mask[i]
+		}
+		bcasts = append(bcasts, b)
+	}
+	return bcasts, nil
+}
@@ -0,0 +1,47 @@
+package ssdp
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// recentPackets remembers the packets relayed in the last ttl. Broadcasts sent by the relay are
+// also delivered to its own listening socket, so without this they would be relayed back and forth
+// between interfaces indefinitely.
+type recentPackets struct {
+	ttl       time.Duration
+	seen      map[[sha256.Size]byte]time.Time
+	lastSweep time.Time
+}
+
+func newRecentPackets(ttl time.Duration) *recentPackets {
+	return &recentPackets{ttl: ttl, seen: make(map[[sha256.Size]byte]time.Time)}
+}
+
+// check records m and reports whether an identical packet from the same source was first seen
+// within the last ttl. Repeats do not extend the ttl, so a packet sent more often than that is
+// still relayed once per ttl.
+func (rp *recentPackets) check(now time.Time, m Message) bool {
+	// Expired packets are removed at most once per ttl, so the map holds no more than two ttls'
+	// worth of packets.
+	if now.Sub(rp.lastSweep) >= rp.ttl {
+		for k, t := range rp.seen {
+			if now.Sub(t) >= rp.ttl {
+				delete(rp.seen, k)
+			}
+		}
+		rp.lastSweep = now
+	}
+
+	h := sha256.New()
+	h.Write([]byte(m.Protocol + "\x00" + m.SourceIP.String() + "\x00"))
+	h.Write(m.Data)
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+
+	if first, found := rp.seen[key]; found && now.Sub(first) < rp.ttl {
+		return true
+	}
+	rp.seen[key] = now
+	return false
+}
@@ -0,0 +1,59 @@
+package ssdp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecentPackets(t *testing.T) {
+	rp := newRecentPackets(time.Second)
+	now := time.Now()
+	m := Message{
+		Protocol: "broadcast",
+		SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.1.10"), Port: 9},
+		Data:     []byte("hello"),
+	}
+	other := m
+	other.SourceIP = &net.UDPAddr{IP: net.ParseIP("192.168.1.11"), Port: 9}
+
+	assert.False(t, rp.check(now, m))
+	assert.True(t, rp.check(now, m))
+	assert.False(t, rp.check(now, other))
+	assert.False(t, rp.check(now.Add(2*time.Second), m))
+}
+
+func TestRecentPackets_RepeatsDoNotExtendTTL(t *testing.T) {
+	rp := newRecentPackets(time.Second)
+	now := time.Now()
+	m := Message{
+		Protocol: "broadcast",
+		SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.1.10"), Port: 9},
+		Data:     []byte("hello"),
+	}
+
+	// The sender repeats the packet every 400ms, more often than the ttl.
+	assert.False(t, rp.check(now, m))
+	assert.True(t, rp.check(now.Add(400*time.Millisecond), m))
+	assert.True(t, rp.check(now.Add(800*time.Millisecond), m))
+	assert.False(t, rp.check(now.Add(1200*time.Millisecond), m))
+	assert.True(t, rp.check(now.Add(1600*time.Millisecond), m))
+}
+
+func TestRecentPackets_Sweep(t *testing.T) {
+	rp := newRecentPackets(time.Second)
+	now := time.Now()
+	m := Message{SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.1.10"), Port: 9}}
+
+	for i := range 10 {
+		m.Data = []byte{byte(i)}
+		rp.check(now.Add(time.Duration(i)*100*time.Millisecond), m)
+	}
+	assert.Len(t, rp.seen, 10)
+
+	m.Data = []byte("later")
+	rp.check(now.Add(1500*time.Millisecond), m)
+	assert.Len(t, rp.seen, 5)
+}
@@ -0,0 +1,120 @@
+package ssdp
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// descriptionRetryInterval is how long to wait before fetching a description again after a
+// failure.
+const descriptionRetryInterval = 5 * time.Minute
+
+// Description is the part of a UPnP device description document that the relay reports.
+type Description struct {
+	FriendlyName string   `json:"friendlyName"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	ModelName    string   `json:"modelName,omitempty"`
+	SerialNumber string   `json:"serialNumber,omitempty"`
+	Services     []string `json:"services,omitempty"`
+}
+
+// ParseDescription parses a UPnP device description document, returning the details of the root
+// device.
+func ParseDescription(r io.Reader) (Description, error) {
+	var doc struct {
+		XMLName xml.Name `xml:"root"`
+		Device  struct {
+			FriendlyName string `xml:"friendlyName"`
+			Manufacturer string `xml:"manufacturer"`
+			ModelName    string `xml:"modelName"`
+			SerialNumber string `xml:"serialNumber"`
+			Services     []struct {
+				ServiceType string `xml:"serviceType"`
+			} `xml:"serviceList>service"`
+		} `xml:"device"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return Description{}, err
+	}
+
+	d := Description{
+		FriendlyName: doc.Device.FriendlyName,
+		Manufacturer: doc.Device.Manufacturer,
+		ModelName:    doc.Device.ModelName,
+		SerialNumber: doc.Device.SerialNumber,
+	}
+	for _, s := range doc.Device.Services {
+		d.Services = append(d.Services, s.ServiceType)
+	}
+	return d, nil
+}
+
+// descriptionFetcher downloads device descriptions. Since LOCATION headers come from unauthenticated
+// multicast packets, it only fetches plain HTTP URLs whose host is the address the advertisement
+// came from, and does not follow redirects.
+type descriptionFetcher struct {
+	client  *http.Client
+	maxSize int64
+}
+
+func newDescriptionFetcher(timeout time.Duration, maxSize int64) *descriptionFetcher {
+	return &descriptionFetcher{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxSize: maxSize,
+	}
+}
+
+func (f *descriptionFetcher) fetch(ctx context.Context, location string, source net.IP) (Description, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return Description{}, fmt.Errorf("parsing location: %w", err)
+	}
+	if u.Scheme != "http" {
+		return Description{}, fmt.Errorf("unsupported scheme: %q", u.Scheme)
+	}
+	host := u.Hostname()
+	if ip, _, _ := strings.Cut(host, "%"); !net.ParseIP(ip).Equal(source) {
+		return Description{}, fmt.Errorf("location host %s does not match source address %s", host, source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Description{}, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Description{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Description{}, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxSize+1))
+	if err != nil {
+		return Description{}, fmt.Errorf("reading description: %w", err)
+	}
+	if int64(len(body)) > f.maxSize {
+		return Description{}, errors.New("description too large")
+	}
+
+	d, err := ParseDescription(bytes.NewReader(body))
+	if err != nil {
+		return Description{}, fmt.Errorf("parsing description: %w", err)
+	}
+	return d, nil
+}
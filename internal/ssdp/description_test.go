@@ -0,0 +1,167 @@
+package ssdp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>1</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+    <friendlyName>Living Room TV</friendlyName>
+    <manufacturer>Acme</manufacturer>
+    <modelName>TV-1000</modelName>
+    <serialNumber>SN123</serialNumber>
+    <serviceList>
+      <service><serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType></service>
+      <service><serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType></service>
+    </serviceList>
+  </device>
+</root>`
+
+func TestParseDescription(t *testing.T) {
+	d, err := ParseDescription(strings.NewReader(testDescription))
+
+	assert.Nil(t, err)
+	assert.Equal(t, Description{
+		FriendlyName: "Living Room TV",
+		Manufacturer: "Acme",
+		ModelName:    "TV-1000",
+		SerialNumber: "SN123",
+		Services: []string{
+			"urn:schemas-upnp-org:service:AVTransport:1",
+			"urn:schemas-upnp-org:service:RenderingControl:1",
+		},
+	}, d)
+}
+
+func TestDescriptionFetcher(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/desc.xml":
+			_, _ = w.Write([]byte(testDescription))
+		case "/redirect":
+			http.Redirect(w, r, "/desc.xml", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	f := newDescriptionFetcher(time.Second, 4096)
+	localhost := net.ParseIP("127.0.0.1")
+
+	d, err := f.fetch(context.Background(), srv.URL+"/desc.xml", localhost)
+	assert.Nil(t, err)
+	assert.Equal(t, "Living Room TV", d.FriendlyName)
+
+	_, err = f.fetch(context.Background(), srv.URL+"/desc.xml", net.ParseIP("192.168.1.10"))
+	assert.ErrorContains(t, err, "does not match source address")
+
+	_, err = f.fetch(context.Background(), strings.Replace(srv.URL, "http:", "https:", 1)+"/desc.xml", localhost)
+	assert.ErrorContains(t, err, "unsupported scheme")
+
+	_, err = f.fetch(context.Background(), srv.URL+"/redirect", localhost)
+	assert.ErrorContains(t, err, "unexpected status")
+
+	_, err = newDescriptionFetcher(time.Second, 100).fetch(context.Background(), srv.URL+"/desc.xml", localhost)
+	assert.ErrorContains(t, err, "too large")
+}
+
+func TestRegistry_Describe(t *testing.T) {
+	r := NewRegistry()
+	var fetches atomic.Int32
+	r.fetch = func(context.Context, string, net.IP) (Description, error) {
+		fetches.Add(1)
+		return Description{FriendlyName: "Living Room TV"}, nil
+	}
+	now := time.Now()
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", "CONFIGID.UPNP.ORG: 1")
+	assert.Eventually(t, func() bool {
+		return r.friendlyName("udp4", "uuid:1234::upnp:rootdevice") == "Living Room TV"
+	}, time.Second, time.Millisecond)
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", "CONFIGID.UPNP.ORG: 1")
+	assert.Equal(t, int32(1), fetches.Load())
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", "CONFIGID.UPNP.ORG: 2")
+	assert.Eventually(t, func() bool {
+		return fetches.Load() == 2 && r.Devices(now)[0].Description != nil
+	}, time.Second, time.Millisecond)
+}
+
+func TestRegistry_DeviceStats(t *testing.T) {
+	r := NewRegistry()
+	r.fetch = func(context.Context, string, net.IP) (Description, error) {
+		return Description{FriendlyName: "Living Room TV"}, nil
+	}
+	now := time.Now()
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive")
+	r.count("udp4", "uuid:1234::upnp:rootdevice", true)
+	r.count("udp4", "uuid:1234::upnp:rootdevice", true)
+	r.count("udp4", "uuid:1234::urn:schemas-upnp-org:device:MediaRenderer:1", false)
+	r.count("udp4", "uuid:5678::upnp:rootdevice", true)
+	r.count("udp6", "uuid:1234::upnp:rootdevice", true)
+
+	assert.Eventually(t, func() bool {
+		return r.friendlyName("udp4", "uuid:1234::upnp:rootdevice") != ""
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []DeviceStats{{
+		UUID:         "uuid:1234",
+		IfName:       "eth1",
+		Network:      "udp4",
+		FriendlyName: "Living Room TV",
+		Stats:        Stats{Received: 3, Relayed: 2, Dropped: 1},
+	}}, r.DeviceStats(now))
+}
+
+func TestRegistry_DescribeConcurrency(t *testing.T) {
+	r := NewRegistry()
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	r.fetch = func(context.Context, string, net.IP) (Description, error) {
+		n := running.Add(1)
+		for {
+			if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return Description{FriendlyName: "TV"}, nil
+	}
+	now := time.Now()
+
+	for i := range 2 * maxDescriptionFetches {
+		observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", fmt.Sprintf("USN: uuid:%d::upnp:rootdevice", i))
+	}
+	assert.Eventually(t, func() bool { return running.Load() == maxDescriptionFetches }, time.Second, time.Millisecond)
+	close(release)
+	assert.Eventually(t, func() bool { return running.Load() == 0 && len(r.fetchSlots) == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(maxDescriptionFetches), peak.Load())
+
+	// Devices skipped while every slot was busy are described when they next advertise.
+	for i := range 2 * maxDescriptionFetches {
+		observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", fmt.Sprintf("USN: uuid:%d::upnp:rootdevice", i))
+	}
+	assert.Eventually(t, func() bool {
+		for _, d := range r.Devices(now) {
+			if d.Description == nil {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,62 @@
+package ssdp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/edutko/go-forward-ssdp/internal/netutil"
+)
+
+// searchGuard limits how many M-SEARCH requests are relayed, so that a single (possibly spoofed)
+// search cannot be used to solicit a flood of unicast replies from every device on another network.
+type searchGuard struct {
+	window      time.Duration
+	perSource   int
+	perTarget   int
+	allowPublic bool
+
+	windowStart time.Time
+	sources     map[string]int
+	targets     map[string]int
+}
+
+func newSearchGuard(window time.Duration, perSource, perTarget int) *searchGuard {
+	return &searchGuard{
+		window:    window,
+		perSource: perSource,
+		perTarget: perTarget,
+		sources:   make(map[string]int),
+		targets:   make(map[string]int),
+	}
+}
+
+// allow reports whether a search from src for the given search target may be relayed. If not,
+// the returned string describes why.
+func (g *searchGuard) allow(now time.Time, src net.IP, st string) (bool, string) {
+	if src == nil {
+		return false, "unknown source address"
+	}
+	if !g.allowPublic && !netutil.IsPrivateIP(src) {
+		return false, "source address is public"
+	}
+
+	if now.Sub(g.windowStart) >= g.window {
+		g.windowStart = now
+		clear(g.sources)
+		clear(g.targets)
+	}
+
+	source := src.String()
+	if g.perSource > 0 && g.sources[source] >= g.perSource {
+		return false, fmt.Sprintf("at least %d searches from source in %s", g.perSource, g.window)
+	}
+	if g.perTarget > 0 && g.targets[st] >= g.perTarget {
+		return false, fmt.Sprintf("at least %d searches for ST in %s", g.perTarget, g.window)
+	}
+
+	g.sources[source]++
+	g.targets[st]++
+
+	return true, ""
+}
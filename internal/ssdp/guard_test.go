@@ -0,0 +1,60 @@
+package ssdp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchGuard_PublicSource(t *testing.T) {
+	g := newSearchGuard(time.Second, 10, 10)
+
+	ok, _ := g.allow(time.Now(), net.ParseIP("8.8.8.8"), "ssdp:all")
+	assert.False(t, ok)
+
+	g.allowPublic = true
+	ok, _ = g.allow(time.Now(), net.ParseIP("8.8.8.8"), "ssdp:all")
+	assert.True(t, ok)
+}
+
+func TestSearchGuard_CGNATSource(t *testing.T) {
+	g := newSearchGuard(time.Second, 10, 10)
+
+	ok, reason := g.allow(time.Now(), net.ParseIP("100.64.12.34"), "ssdp:all")
+	assert.False(t, ok)
+	assert.Equal(t, "source address is public", reason)
+
+	ok, _ = g.allow(time.Now(), net.ParseIP("10.0.0.5"), "ssdp:all")
+	assert.True(t, ok)
+}
+
+func TestSearchGuard_PerSourceLimit(t *testing.T) {
+	g := newSearchGuard(time.Second, 2, 0)
+	now := time.Now()
+
+	assert.True(t, allowed(g, now, "192.168.1.10", "ssdp:all"))
+	assert.True(t, allowed(g, now, "192.168.1.10", "roku:ecp"))
+	assert.False(t, allowed(g, now, "192.168.1.10", "upnp:rootdevice"))
+	assert.True(t, allowed(g, now, "192.168.1.11", "ssdp:all"))
+	assert.True(t, allowed(g, now.Add(time.Second), "192.168.1.10", "ssdp:all"))
+}
+
+func TestSearchGuard_PerTargetLimit(t *testing.T) {
+	g := newSearchGuard(time.Second, 0, 2)
+	now := time.Now()
+
+	assert.True(t, allowed(g, now, "192.168.1.10", "ssdp:all"))
+	assert.True(t, allowed(g, now, "192.168.1.11", "ssdp:all"))
+	assert.False(t, allowed(g, now, "192.168.1.12", "ssdp:all"))
+	assert.True(t, allowed(g, now, "192.168.1.12", "roku:ecp"))
+
+	_, reason := g.allow(now, net.ParseIP("192.168.1.13"), "ssdp:all")
+	assert.Equal(t, "at least 2 searches for ST in 1s", reason)
+}
+
+func allowed(g *searchGuard, now time.Time, src, st string) bool {
+	ok, _ := g.allow(now, net.ParseIP(src), st)
+	return ok
+}
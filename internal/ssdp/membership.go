@@ -0,0 +1,74 @@
+package ssdp
+
+import (
+	"log/slog"
+	"net"
+	"runtime"
+
+	"github.com/edutko/go-forward-ssdp/internal/netutil"
+)
+
+// membershipProblem is a reason the relay may not receive a multicast group on an interface.
+type membershipProblem struct {
+	ifName  string
+	group   string
+	problem string
+}
+
+var hasJoinedGroup = func(ifi net.Interface, group net.IP) (bool, error) {
+	matching, err := netutil.FilterInterfaces([]net.Interface{ifi}, netutil.FailOnError(), netutil.HasMulticastGroup(group.String()))
+	return len(matching) > 0, err
+}
+
+var snoopingWarning = netutil.MulticastSnoopingWarning
+
+// checkMemberships verifies that the kernel has joined each of the protocols' groups on the
+// interfaces listened on, and looks for conditions that would stop the groups being received.
+func checkMemberships(ifs []net.Interface, protocols []Protocol) []membershipProblem {
+	var problems []membershipProblem
+	for _, ifi := range ifs {
+		var groups []Group
+		for _, p := range protocols {
+			for _, g := range p.Groups {
+				if g.Network() == "udp6" && runtime.GOOS == "windows" || !g.onInterface(ifi.Name) {
+					continue
+				}
+				groups = append(groups, g)
+			}
+		}
+		if len(groups) == 0 {
+			continue
+		}
+
+		if ifi.Flags&net.FlagMulticast == 0 {
+			problems = append(problems, membershipProblem{ifi.Name, "", "interface is not multicast-capable"})
+			continue
+		}
+		for _, g := range groups {
+			joined, err := hasJoinedGroup(ifi, g.Addr.IP)
+			if err != nil {
+				problems = append(problems, membershipProblem{ifi.Name, g.Addr.IP.String(), "could not verify membership: " + err.Error()})
+			} else if !joined {
+				problems = append(problems, membershipProblem{ifi.Name, g.Addr.IP.String(), "group has not been joined"})
+			}
+		}
+
+		w, err := snoopingWarning(ifi)
+		if err != nil {
+			problems = append(problems, membershipProblem{ifi.Name, "", "could not check for IGMP snooping: " + err.Error()})
+		} else if w != "" {
+			problems = append(problems, membershipProblem{ifi.Name, "", w})
+		}
+	}
+	return problems
+}
+
+func logMembershipProblems(problems []membershipProblem) {
+	for _, p := range problems {
+		attrs := []any{"interface", p.ifName, "problem", p.problem}
+		if p.group != "" {
+			attrs = append(attrs, "group", p.group)
+		}
+		slog.Warn("multicast reception may fail", attrs...)
+	}
+}
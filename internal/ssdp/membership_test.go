@@ -0,0 +1,52 @@
+package ssdp
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMemberships(t *testing.T) {
+	origHasJoinedGroup, origSnoopingWarning := hasJoinedGroup, snoopingWarning
+	defer func() {
+		hasJoinedGroup, snoopingWarning = origHasJoinedGroup, origSnoopingWarning
+	}()
+	hasJoinedGroup = func(ifi net.Interface, group net.IP) (bool, error) {
+		switch ifi.Name {
+		case "eth1":
+			return group.To4() != nil, nil
+		case "eth2":
+			return false, errors.New("not supported")
+		}
+		return true, nil
+	}
+	snoopingWarning = func(ifi net.Interface) (string, error) {
+		if ifi.Name == "br0" {
+			return "bridge br0 snoops IGMP/MLD but is not a querier", nil
+		}
+		return "", nil
+	}
+
+	link, err := ParseSSDPIPv6Scope("link")
+	assert.Nil(t, err)
+	protocols := []Protocol{SSDP(nil, link)}
+	ifs := []net.Interface{
+		{Name: "eth0", Flags: net.FlagUp | net.FlagMulticast},
+		{Name: "eth1", Flags: net.FlagUp | net.FlagMulticast},
+		{Name: "eth2", Flags: net.FlagUp | net.FlagMulticast},
+		{Name: "br0", Flags: net.FlagUp | net.FlagMulticast},
+		{Name: "tun0", Flags: net.FlagUp | net.FlagPointToPoint},
+	}
+
+	problems := checkMemberships(ifs, protocols)
+
+	assert.Equal(t, []membershipProblem{
+		{"eth1", "ff02::c", "group has not been joined"},
+		{"eth2", "239.255.255.250", "could not verify membership: not supported"},
+		{"eth2", "ff02::c", "could not verify membership: not supported"},
+		{"br0", "", "bridge br0 snoops IGMP/MLD but is not a querier"},
+		{"tun0", "", "interface is not multicast-capable"},
+	}, problems)
+}
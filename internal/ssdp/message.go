@@ -0,0 +1,107 @@
+package ssdp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+)
+
+type MessageType int
+
+const (
+	UnknownMessage MessageType = iota
+	NotifyMessage
+	SearchMessage
+	ResponseMessage
+)
+
+func (t MessageType) String() string {
+	switch t {
+	case NotifyMessage:
+		return "NOTIFY"
+	case SearchMessage:
+		return "M-SEARCH"
+	case ResponseMessage:
+		return "response"
+	default:
+		return "unknown"
+	}
+}
+
+// Packet is the parsed start line and headers of an SSDP message. Header names are stored in
+// upper case.
+type Packet struct {
+	Type    MessageType
+	Headers map[string]string
+}
+
+var errEmptyMessage = errors.New("empty message")
+
+func ParsePacket(data []byte) (Packet, error) {
+	lines := strings.Split(string(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+		return Packet{}, errEmptyMessage
+	}
+
+	p := Packet{Type: parseStartLine(lines[0]), Headers: make(map[string]string)}
+	for _, line := range lines[1:] {
+		if line == "" {
+			break
+		}
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		p.Headers[strings.ToUpper(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+
+	return p, nil
+}
+
+func parseStartLine(line string) MessageType {
+	switch {
+	case strings.HasPrefix(line, "NOTIFY "):
+		return NotifyMessage
+	case strings.HasPrefix(line, "M-SEARCH "):
+		return SearchMessage
+	case strings.HasPrefix(line, "HTTP/"):
+		return ResponseMessage
+	default:
+		return UnknownMessage
+	}
+}
+
+func (p Packet) Get(name string) string {
+	return p.Headers[strings.ToUpper(name)]
+}
+
+func (p Packet) ST() string {
+	return p.Get("ST")
+}
+
+func (p Packet) NT() string {
+	return p.Get("NT")
+}
+
+func (p Packet) NTS() string {
+	return p.Get("NTS")
+}
+
+func (p Packet) USN() string {
+	return p.Get("USN")
+}
+
+func (p Packet) Location() string {
+	return p.Get("LOCATION")
+}
+
+func (p Packet) logAttrs() []any {
+	attrs := []any{"type", p.Type.String()}
+	if st := p.ST(); st != "" {
+		attrs = append(attrs, "st", st)
+	}
+	if nt := p.NT(); nt != "" {
+		attrs = append(attrs, "nt", nt)
+	}
+	return attrs
+}
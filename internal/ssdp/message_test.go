@@ -0,0 +1,50 @@
+package ssdp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePacket_MSearch(t *testing.T) {
+	p, err := ParsePacket([]byte("M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: 239.255.255.250:1900\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 1\r\n" +
+		"st: roku:ecp\r\n" +
+		"\r\n"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, SearchMessage, p.Type)
+	assert.Equal(t, "roku:ecp", p.ST())
+	assert.Equal(t, "1", p.Get("mx"))
+}
+
+func TestParsePacket_Notify(t *testing.T) {
+	p, err := ParsePacket([]byte("NOTIFY * HTTP/1.1\n" +
+		"HOST: 239.255.255.250:1900\n" +
+		"NT: upnp:rootdevice\n" +
+		"NTS: ssdp:alive\n" +
+		"LOCATION: http://192.168.1.10:8060/\n" +
+		"USN: uuid:abcd::upnp:rootdevice\n"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, NotifyMessage, p.Type)
+	assert.Equal(t, "upnp:rootdevice", p.NT())
+	assert.Equal(t, "ssdp:alive", p.NTS())
+	assert.Equal(t, "http://192.168.1.10:8060/", p.Location())
+	assert.Equal(t, "uuid:abcd::upnp:rootdevice", p.USN())
+}
+
+func TestParsePacket_Response(t *testing.T) {
+	p, err := ParsePacket([]byte("HTTP/1.1 200 OK\r\nST: ssdp:all\r\n\r\n"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, ResponseMessage, p.Type)
+	assert.Equal(t, "ssdp:all", p.ST())
+}
+
+func TestParsePacket_Empty(t *testing.T) {
+	_, err := ParsePacket([]byte{})
+	assert.NotNil(t, err)
+}
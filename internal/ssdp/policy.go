@@ -0,0 +1,69 @@
+package ssdp
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SSDPPolicy relays SSDP messages, limiting how many M-SEARCH requests are forwarded. Inspect must
+// only be called from one goroutine at a time.
+type SSDPPolicy struct {
+	guard *searchGuard
+}
+
+type SSDPOption func(p *SSDPPolicy) error
+
+func NewSSDPPolicy(opts ...SSDPOption) (*SSDPPolicy, error) {
+	p := &SSDPPolicy{guard: newSearchGuard(5*time.Second, 10, 20)}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, fmt.Errorf("configuring SSDP policy: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// WithSearchLimits limits the number of M-SEARCH requests relayed per source address and per
+// search target (ST) within each window. A limit of 0 disables that check.
+func WithSearchLimits(window time.Duration, perSource, perTarget int) SSDPOption {
+	return func(p *SSDPPolicy) error {
+		if window <= 0 {
+			return fmt.Errorf("invalid search window: %s", window)
+		}
+		if perSource < 0 || perTarget < 0 {
+			return fmt.Errorf("invalid search limits: %d per source, %d per target", perSource, perTarget)
+		}
+		p.guard.window = window
+		p.guard.perSource = perSource
+		p.guard.perTarget = perTarget
+		return nil
+	}
+}
+
+// AllowPublicSearchSources permits relaying M-SEARCH requests from public (routable) addresses.
+func AllowPublicSearchSources() SSDPOption {
+	return func(p *SSDPPolicy) error {
+		p.guard.allowPublic = true
+		return nil
+	}
+}
+
+func (p *SSDPPolicy) Inspect(m Message) Verdict {
+	pkt, err := m.packet()
+	if err != nil {
+		return Verdict{Reason: fmt.Sprintf("error parsing packet: %s", err.Error()), Level: slog.LevelWarn}
+	}
+	v := Verdict{Relay: true, Attrs: pkt.logAttrs()}
+
+	if pkt.Type == SearchMessage {
+		ip, _ := m.Source()
+		if ok, reason := p.guard.allow(time.Now(), ip, pkt.ST()); !ok {
+			v.Relay = false
+			v.Reason = reason
+			v.Level = slog.LevelWarn
+		}
+	}
+
+	return v
+}
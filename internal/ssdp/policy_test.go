@@ -0,0 +1,38 @@
+package ssdp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSDPPolicy_Inspect(t *testing.T) {
+	p, err := NewSSDPPolicy()
+	assert.Nil(t, err)
+	m := Message{
+		SourceIP: &net.UDPAddr{IP: net.ParseIP("8.8.8.8"), Port: 1900},
+		Data:     []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nST: ssdp:all\r\n\r\n"),
+	}
+
+	v := p.Inspect(m)
+	assert.False(t, v.Relay)
+	assert.Equal(t, "source address is public", v.Reason)
+
+	v = p.Inspect(Message{Data: []byte("\r\n")})
+	assert.False(t, v.Relay)
+	assert.Contains(t, v.Reason, "error parsing packet")
+}
+
+func TestSSDPPolicy_InspectUsesParsedPacket(t *testing.T) {
+	p, err := NewSSDPPolicy()
+	assert.Nil(t, err)
+	m := Message{
+		SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.1.10"), Port: 1900},
+		Data:     []byte("\r\n"),
+		parsed:   &parsedPacket{packet: Packet{Type: NotifyMessage, Headers: map[string]string{}}},
+	}
+
+	v := p.Inspect(m)
+	assert.True(t, v.Relay)
+}
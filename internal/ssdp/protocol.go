@@ -0,0 +1,186 @@
+package ssdp
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Group is a multicast group address and port, and the hop limit (TTL) used when relaying packets
+// to it.
+type Group struct {
+	Addr     *net.UDPAddr
+	HopLimit int
+	// Interfaces restricts the group to the named interfaces. If empty, the group is joined and
+	// relayed to on every interface.
+	Interfaces []string
+}
+
+func (g Group) onInterface(name string) bool {
+	return len(g.Interfaces) == 0 || slices.Contains(g.Interfaces, name)
+}
+
+func (g Group) Network() string {
+	if g.Addr.IP.To4() != nil {
+		return "udp4"
+	}
+	return "udp6"
+}
+
+func (g Group) String() string {
+	return g.Addr.String()
+}
+
+// Protocol describes a UDP multicast service to be relayed.
+type Protocol struct {
+	Name   string
+	Groups []Group
+	// Ports are UDP ports on which IPv4 broadcasts are relayed.
+	Ports []int
+	// PacketLimit is the maximum number of packets of this protocol relayed per throttle interval.
+	// A value of 0 uses the relay's default.
+	PacketLimit uint64
+	// Policy decides which messages are relayed. If nil, every message is relayed.
+	Policy Policy
+}
+
+// Policy examines received messages and decides whether they should be relayed.
+type Policy interface {
+	Inspect(m Message) Verdict
+}
+
+type Verdict struct {
+	Relay bool
+	// Reason explains why a message is not relayed.
+	Reason string
+	// Level is the level at which a rejected message is logged.
+	Level slog.Level
+	// Attrs describe the message in log entries.
+	Attrs []any
+	// Targets are the names of the interfaces to relay the message to. If empty, it is relayed to
+	// every interface except the one it was received on.
+	Targets []string
+}
+
+// SSDP returns the SSDP protocol, using the given IPv6 groups (see ParseSSDPIPv6Scope) or, if none
+// are given, only the link-local group.
+func SSDP(policy Policy, ipv6Groups ...Group) Protocol {
+	if len(ipv6Groups) == 0 {
+		ipv6Groups = []Group{ssdpIPv6Scopes["link"]}
+	}
+	return Protocol{
+		Name:   "ssdp",
+		Groups: append([]Group{{Addr: ipv4UDPAddr, HopLimit: 1}}, ipv6Groups...),
+		Policy: policy,
+	}
+}
+
+// ssdpIPv6Scopes are the IPv6 multicast groups defined for SSDP by the UPnP Device Architecture,
+// with their default hop limits.
+var ssdpIPv6Scopes = map[string]Group{
+	"link":         {Addr: ipv6LinkLocalUDPAddr, HopLimit: 1},
+	"site":         {Addr: ipv6SiteLocalUDPAddr, HopLimit: 2},
+	"organization": {Addr: ipv6OrgLocalUDPAddr, HopLimit: 2},
+}
+
+// ParseSSDPIPv6Scope parses an SSDP IPv6 scope specification of the form
+//
+//	scope[/hoplimit][:interface,...]
+//
+// where scope is "link" (ff02::c), "site" (ff05::c) or "organization" (ff08::c), e.g.
+// "site/4:vlan10,vlan20". If no interfaces are given, the scope is used on every interface.
+func ParseSSDPIPv6Scope(spec string) (Group, error) {
+	scope, ifNames, _ := strings.Cut(spec, ":")
+	scope, hops, hasHops := strings.Cut(scope, "/")
+	if scope == "org" {
+		scope = "organization"
+	}
+
+	g, found := ssdpIPv6Scopes[scope]
+	if !found {
+		return Group{}, fmt.Errorf("invalid IPv6 scope %q: unknown scope: %q", spec, scope)
+	}
+	if hasHops {
+		h, err := strconv.Atoi(hops)
+		if err != nil || h < 1 || h > 255 {
+			return Group{}, fmt.Errorf("invalid IPv6 scope %q: invalid hop limit: %q", spec, hops)
+		}
+		g.HopLimit = h
+	}
+	for _, name := range strings.Split(ifNames, ",") {
+		if name != "" {
+			g.Interfaces = append(g.Interfaces, name)
+		}
+	}
+
+	return g, nil
+}
+
+// ParseProtocol parses a protocol specification of the form
+//
+//	name=group[,group...][,limit=N]
+//
+// where each group is a multicast address and port, optionally followed by "/" and a hop limit
+// (default 1), e.g. "wsd=239.255.255.250:3702,[ff02::c]:3702,limit=100".
+func ParseProtocol(spec string) (Protocol, error) {
+	name, rest, found := strings.Cut(spec, "=")
+	if !found || name == "" || rest == "" {
+		return Protocol{}, fmt.Errorf("invalid protocol %q: expected name=group[,group...]", spec)
+	}
+
+	p := Protocol{Name: name}
+	for _, field := range strings.Split(rest, ",") {
+		if v, ok := strings.CutPrefix(field, "limit="); ok {
+			limit, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return Protocol{}, fmt.Errorf("invalid protocol %q: invalid limit: %q", spec, v)
+			}
+			p.PacketLimit = limit
+			continue
+		}
+
+		g, err := parseGroup(field)
+		if err != nil {
+			return Protocol{}, fmt.Errorf("invalid protocol %q: %w", spec, err)
+		}
+		p.Groups = append(p.Groups, g)
+	}
+
+	if len(p.Groups) == 0 {
+		return Protocol{}, fmt.Errorf("invalid protocol %q: no groups", spec)
+	}
+
+	return p, nil
+}
+
+func parseGroup(s string) (Group, error) {
+	g := Group{HopLimit: 1}
+
+	addr, hops, found := strings.Cut(s, "/")
+	if found {
+		h, err := strconv.Atoi(hops)
+		if err != nil || h < 1 || h > 255 {
+			return Group{}, fmt.Errorf("invalid hop limit: %q", hops)
+		}
+		g.HopLimit = h
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return Group{}, fmt.Errorf("invalid group %q: %w", addr, err)
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsMulticast() {
+		return Group{}, fmt.Errorf("invalid group %q: not a multicast address", addr)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return Group{}, fmt.Errorf("invalid group %q: invalid port", addr)
+	}
+	g.Addr = &net.UDPAddr{IP: ip, Port: p}
+
+	return g, nil
+}
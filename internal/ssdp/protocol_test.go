@@ -0,0 +1,75 @@
+package ssdp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProtocol(t *testing.T) {
+	p, err := ParseProtocol("wsd=239.255.255.250:3702,[ff05::c]:3702/8,limit=100")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "wsd", p.Name)
+	assert.Equal(t, uint64(100), p.PacketLimit)
+	assert.Len(t, p.Groups, 2)
+	assert.Equal(t, "239.255.255.250:3702", p.Groups[0].String())
+	assert.Equal(t, "udp4", p.Groups[0].Network())
+	assert.Equal(t, 1, p.Groups[0].HopLimit)
+	assert.Equal(t, "[ff05::c]:3702", p.Groups[1].String())
+	assert.Equal(t, "udp6", p.Groups[1].Network())
+	assert.Equal(t, 8, p.Groups[1].HopLimit)
+}
+
+func TestParseProtocol_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"wsd",
+		"wsd=",
+		"wsd=limit=5",
+		"wsd=192.168.1.1:3702",
+		"wsd=239.255.255.250",
+		"wsd=239.255.255.250:0",
+		"wsd=239.255.255.250:3702/0",
+		"wsd=239.255.255.250:3702,limit=lots",
+	} {
+		_, err := ParseProtocol(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestSSDP_IPv6Scopes(t *testing.T) {
+	p := SSDP(nil)
+	assert.Len(t, p.Groups, 2)
+	assert.Equal(t, "[ff02::c]:1900", p.Groups[1].String())
+
+	site, err := ParseSSDPIPv6Scope("site")
+	assert.Nil(t, err)
+	p = SSDP(nil, site)
+	assert.Len(t, p.Groups, 2)
+	assert.Equal(t, "239.255.255.250:1900", p.Groups[0].String())
+	assert.Equal(t, "[ff05::c]:1900", p.Groups[1].String())
+	assert.Equal(t, 2, p.Groups[1].HopLimit)
+}
+
+func TestParseSSDPIPv6Scope(t *testing.T) {
+	g, err := ParseSSDPIPv6Scope("org/4:vlan10,vlan20")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "[ff08::c]:1900", g.String())
+	assert.Equal(t, 4, g.HopLimit)
+	assert.Equal(t, []string{"vlan10", "vlan20"}, g.Interfaces)
+	assert.True(t, g.onInterface("vlan10"))
+	assert.False(t, g.onInterface("eth0"))
+
+	g, err = ParseSSDPIPv6Scope("link")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, g.HopLimit)
+	assert.Nil(t, g.Interfaces)
+	assert.True(t, g.onInterface("eth0"))
+
+	for _, spec := range []string{"", "global", "site/0", "site/256", "site/x"} {
+		_, err := ParseSSDPIPv6Scope(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
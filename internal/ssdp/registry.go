@@ -0,0 +1,398 @@
+package ssdp
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxAge = 1800 * time.Second
+	// maxMaxAge bounds how long a device is remembered without hearing from it again.
+	maxMaxAge         = 24 * time.Hour
+	deviceHistorySize = 16
+	// maxDevices bounds the number of devices tracked, since any host on a relayed network can
+	// advertise as many as it likes. Advertisements from further devices are ignored.
+	maxDevices = 1024
+	// maxDescriptionFetches bounds the number of descriptions fetched at once. A device whose
+	// description cannot be fetched yet is tried again when it next advertises itself.
+	maxDescriptionFetches = 8
+)
+
+// Device is a UPnP device known from the NOTIFY messages it has sent.
+type Device struct {
+	UUID     string `json:"uuid"`
+	IfName   string `json:"interface"`
+	Network  string `json:"network"`
+	IP       net.IP `json:"ip"`
+	Location string `json:"location,omitempty"`
+	Server   string `json:"server,omitempty"`
+	// SearchPort is the port on which the device accepts unicast M-SEARCH requests, or 0 if it
+	// is not known to accept them. UPnP 1.1 and later devices accept them on port 1900 unless
+	// they advertise another port with SEARCHPORT.UPNP.ORG.
+	SearchPort int `json:"searchPort,omitempty"`
+	// BootID and ConfigID are the device's BOOTID.UPNP.ORG and CONFIGID.UPNP.ORG, or -1 if it has
+	// not advertised them.
+	BootID   int           `json:"bootId"`
+	ConfigID int           `json:"configId"`
+	LastSeen time.Time     `json:"lastSeen"`
+	Expires  time.Time     `json:"expires"`
+	History  []DeviceEvent `json:"history"`
+	// Description is the device's description document, if it has been fetched.
+	Description *Description `json:"description,omitempty"`
+
+	describedAs    describedAs
+	describing     bool
+	describeFailed time.Time
+	stats          Stats
+}
+
+// DeviceStats counts the SSDP packets from a device since it was discovered, labeled with its
+// friendly name if its description has been fetched.
+type DeviceStats struct {
+	UUID         string `json:"uuid"`
+	IfName       string `json:"interface"`
+	Network      string `json:"network"`
+	FriendlyName string `json:"friendlyName,omitempty"`
+	Stats
+}
+
+// describedAs identifies the advertisement a description was fetched for, so that it is fetched
+// again if the device moves or changes its configuration.
+type describedAs struct {
+	location string
+	configID int
+}
+
+type DeviceEventKind string
+
+const (
+	DeviceDiscovered DeviceEventKind = "discovered"
+	// DeviceRestarted means the device's BOOTID changed without an ssdp:update, including going
+	// backwards.
+	DeviceRestarted DeviceEventKind = "restarted"
+	// DeviceUpdated means the device announced a new BOOTID with ssdp:update, e.g. because it
+	// joined another network, without restarting.
+	DeviceUpdated       DeviceEventKind = "updated"
+	DeviceConfigChanged DeviceEventKind = "config-changed"
+)
+
+// DeviceEvent is a change in a device's boot or configuration state, with the IDs that resulted.
+type DeviceEvent struct {
+	Time     time.Time       `json:"time"`
+	Kind     DeviceEventKind `json:"kind"`
+	BootID   int             `json:"bootId"`
+	ConfigID int             `json:"configId"`
+}
+
+// Registry tracks the devices advertising themselves on each interface. It is safe for
+// concurrent use.
+type Registry struct {
+	mu         sync.Mutex
+	devices    map[registryKey]*Device
+	maxDevices int
+	fetch      func(ctx context.Context, location string, source net.IP) (Description, error)
+	fetchSlots chan struct{}
+}
+
+type registryKey struct {
+	network string
+	uuid    string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		devices:    make(map[registryKey]*Device),
+		maxDevices: maxDevices,
+		fetchSlots: make(chan struct{}, maxDescriptionFetches),
+	}
+}
+
+// Observe records the device that sent p, if p is a NOTIFY message, and returns any changes to
+// its boot or configuration state. Devices are removed when they send ssdp:byebye or their
+// advertisement expires.
+func (r *Registry) Observe(now time.Time, m Message, p Packet) []DeviceEvent {
+	if p.Type != NotifyMessage {
+		return nil
+	}
+	uuid := deviceUUID(p.USN())
+	if uuid == "" {
+		return nil
+	}
+	ip, _ := m.Source()
+	if ip == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(now)
+
+	key := registryKey{m.Network, uuid}
+	if p.NTS() == "ssdp:byebye" {
+		delete(r.devices, key)
+		return nil
+	}
+
+	d, found := r.devices[key]
+	if !found {
+		if len(r.devices) >= r.maxDevices {
+			return nil
+		}
+		d = &Device{UUID: uuid, Network: m.Network, BootID: -1, ConfigID: -1, Expires: now.Add(maxAge(p))}
+		r.devices[key] = d
+	}
+	d.IfName = m.IfName
+	d.IP = ip
+	d.LastSeen = now
+	if p.NTS() != "ssdp:update" {
+		// ssdp:update does not carry CACHE-CONTROL
+		d.Expires = now.Add(maxAge(p))
+	}
+	if loc := p.Location(); loc != "" {
+		d.Location = loc
+	}
+	if server := p.Get("SERVER"); server != "" {
+		d.Server = server
+	}
+	if port := searchPort(p); port != 0 {
+		d.SearchPort = port
+	}
+
+	events := d.observeIDs(now, p)
+	if !found {
+		events = append(events, d.event(now, DeviceDiscovered))
+	}
+	d.History = append(d.History, events...)
+	if len(d.History) > deviceHistorySize {
+		d.History = slices.Clone(d.History[len(d.History)-deviceHistorySize:])
+	}
+	r.describe(now, key, d)
+	return events
+}
+
+// describe starts fetching the device's description if descriptions are enabled and the one
+// cached is missing or out of date.
+func (r *Registry) describe(now time.Time, key registryKey, d *Device) {
+	if r.fetch == nil || d.Location == "" || d.describing {
+		return
+	}
+	want := describedAs{d.Location, d.ConfigID}
+	if d.describedAs == want && (d.Description != nil || now.Sub(d.describeFailed) < descriptionRetryInterval) {
+		return
+	}
+	select {
+	case r.fetchSlots <- struct{}{}:
+	default:
+		return
+	}
+	if d.describedAs != want {
+		d.Description = nil
+	}
+	d.describedAs = want
+	d.describing = true
+
+	ip := d.IP
+	go func() {
+		desc, err := r.fetch(context.Background(), want.location, ip)
+		<-r.fetchSlots
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		d, found := r.devices[key]
+		if !found || d.describedAs != want {
+			return
+		}
+		d.describing = false
+		if err != nil {
+			d.describeFailed = time.Now()
+			slog.Debug("error fetching device description", "usn", key.uuid, "location", want.location, "error", err)
+			return
+		}
+		d.Description = &desc
+	}()
+}
+
+// friendlyName returns the friendly name of the device that sent usn, if its description has
+// been fetched.
+func (r *Registry) friendlyName(network, usn string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if d, found := r.devices[registryKey{network, deviceUUID(usn)}]; found && d.Description != nil {
+		return d.Description.FriendlyName
+	}
+	return ""
+}
+
+// count records whether a packet from the device that sent usn was relayed. Packets from unknown
+// devices are not counted.
+func (r *Registry) count(network, usn string, relayed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, found := r.devices[registryKey{network, deviceUUID(usn)}]
+	if !found {
+		return
+	}
+	d.stats.Received++
+	if relayed {
+		d.stats.Relayed++
+	} else {
+		d.stats.Dropped++
+	}
+}
+
+// observeIDs updates the device's BOOTID and CONFIGID from p and returns any changes.
+func (d *Device) observeIDs(now time.Time, p Packet) []DeviceEvent {
+	var events []DeviceEvent
+	bootID, hasBootID := headerID(p, "BOOTID.UPNP.ORG")
+	configID, hasConfigID := headerID(p, "CONFIGID.UPNP.ORG")
+
+	known := d.BootID >= 0
+	switch {
+	case p.NTS() == "ssdp:update":
+		if next, ok := headerID(p, "NEXTBOOTID.UPNP.ORG"); ok && next != d.BootID {
+			d.BootID = next
+			if known {
+				events = append(events, d.event(now, DeviceUpdated))
+			}
+		}
+	case hasBootID && bootID != d.BootID:
+		d.BootID = bootID
+		if known {
+			d.SearchPort = searchPort(p)
+			events = append(events, d.event(now, DeviceRestarted))
+		}
+	}
+
+	if hasConfigID && configID != d.ConfigID {
+		changed := d.ConfigID >= 0
+		d.ConfigID = configID
+		if changed {
+			events = append(events, d.event(now, DeviceConfigChanged))
+		}
+	}
+	return events
+}
+
+func (d *Device) event(now time.Time, kind DeviceEventKind) DeviceEvent {
+	return DeviceEvent{Time: now, Kind: kind, BootID: d.BootID, ConfigID: d.ConfigID}
+}
+
+// Devices returns the devices whose advertisements have not expired, ordered by interface and
+// UUID.
+func (r *Registry) Devices(now time.Time) []Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prune(now)
+
+	devices := make([]Device, 0, len(r.devices))
+	for _, d := range r.devices {
+		c := *d
+		c.History = slices.Clone(d.History)
+		devices = append(devices, c)
+	}
+	slices.SortFunc(devices, func(a, b Device) int {
+		if c := strings.Compare(a.IfName, b.IfName); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Network, b.Network); c != 0 {
+			return c
+		}
+		return strings.Compare(a.UUID, b.UUID)
+	})
+	return devices
+}
+
+// DeviceStats returns packet counts for the devices whose advertisements have not expired, in the
+// same order as Devices.
+func (r *Registry) DeviceStats(now time.Time) []DeviceStats {
+	devices := r.Devices(now)
+	stats := make([]DeviceStats, 0, len(devices))
+	for _, d := range devices {
+		ds := DeviceStats{UUID: d.UUID, IfName: d.IfName, Network: d.Network, Stats: d.stats}
+		if d.Description != nil {
+			ds.FriendlyName = d.Description.FriendlyName
+		}
+		stats = append(stats, ds)
+	}
+	return stats
+}
+
+// searchTargets returns the unicast search addresses of the devices on ifName. It returns false
+// if no devices are known there, or if any of them does not accept unicast searches, since only a
+// multicast search would reach every device.
+func (r *Registry) searchTargets(now time.Time, ifName, network string) ([]*net.UDPAddr, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var addrs []*net.UDPAddr
+	for _, d := range r.devices {
+		if d.IfName != ifName || d.Network != network || !now.Before(d.Expires) {
+			continue
+		}
+		if d.SearchPort == 0 {
+			return nil, false
+		}
+		addr := &net.UDPAddr{IP: d.IP, Port: d.SearchPort}
+		if !slices.ContainsFunc(addrs, func(a *net.UDPAddr) bool { return a.IP.Equal(addr.IP) && a.Port == addr.Port }) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, len(addrs) > 0
+}
+
+func (r *Registry) prune(now time.Time) {
+	for k, d := range r.devices {
+		if !now.Before(d.Expires) {
+			delete(r.devices, k)
+		}
+	}
+}
+
+// deviceUUID returns the "uuid:..." part of a USN.
+func deviceUUID(usn string) string {
+	uuid, _, _ := strings.Cut(usn, "::")
+	if !strings.HasPrefix(strings.ToLower(uuid), "uuid:") {
+		return ""
+	}
+	return uuid
+}
+
+func maxAge(p Packet) time.Duration {
+	for _, directive := range strings.Split(p.Get("CACHE-CONTROL"), ",") {
+		name, value, found := strings.Cut(directive, "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "max-age") {
+			continue
+		}
+		if secs, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && secs > 0 {
+			return min(time.Duration(secs)*time.Second, maxMaxAge)
+		}
+	}
+	return defaultMaxAge
+}
+
+// headerID parses a BOOTID, NEXTBOOTID or CONFIGID header, which hold non-negative 31-bit integers.
+func headerID(p Packet, name string) (int, bool) {
+	id, err := strconv.ParseInt(p.Get(name), 10, 32)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	return int(id), true
+}
+
+func searchPort(p Packet) int {
+	if s := p.Get("SEARCHPORT.UPNP.ORG"); s != "" {
+		if port, err := strconv.Atoi(s); err == nil && port > 0 && port <= 65535 {
+			return port
+		}
+	}
+	if p.Get("BOOTID.UPNP.ORG") != "" {
+		return ipv4UDPAddr.Port
+	}
+	return 0
+}
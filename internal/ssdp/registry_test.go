@@ -0,0 +1,135 @@
+package ssdp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func observeNotify(r *Registry, now time.Time, ifName, ip, nts string, headers ...string) {
+	data := "NOTIFY * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nCACHE-CONTROL: max-age=60\r\n" +
+		"NT: upnp:rootdevice\r\nNTS: " + nts + "\r\nUSN: uuid:1234::upnp:rootdevice\r\n" +
+		"LOCATION: http://" + ip + ":8080/desc.xml\r\n"
+	for _, h := range headers {
+		data += h + "\r\n"
+	}
+	m := Message{
+		Protocol: "ssdp",
+		Network:  "udp4",
+		IfName:   ifName,
+		SourceIP: &net.UDPAddr{IP: net.ParseIP(ip), Port: 1900},
+		Data:     []byte(data + "\r\n"),
+	}
+	p, _ := ParsePacket(m.Data)
+	r.Observe(now, m, p)
+}
+
+func TestRegistry_Observe(t *testing.T) {
+	r := NewRegistry()
+	now := time.Now()
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", "SEARCHPORT.UPNP.ORG: 49152")
+
+	devices := r.Devices(now)
+	assert.Len(t, devices, 1)
+	assert.Equal(t, "uuid:1234", devices[0].UUID)
+	assert.Equal(t, "eth1", devices[0].IfName)
+	assert.Equal(t, "http://192.168.2.10:8080/desc.xml", devices[0].Location)
+	assert.Equal(t, 49152, devices[0].SearchPort)
+	assert.Equal(t, now.Add(time.Minute), devices[0].Expires)
+
+	assert.Empty(t, r.Devices(now.Add(time.Minute)))
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive")
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:byebye")
+	assert.Empty(t, r.Devices(now))
+}
+
+func TestRegistry_SearchTargets(t *testing.T) {
+	r := NewRegistry()
+	now := time.Now()
+
+	_, ok := r.searchTargets(now, "eth1", "udp4")
+	assert.False(t, ok)
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", "BOOTID.UPNP.ORG: 1")
+	addrs, ok := r.searchTargets(now, "eth1", "udp4")
+	assert.True(t, ok)
+	assert.Equal(t, []*net.UDPAddr{{IP: net.ParseIP("192.168.2.10"), Port: 1900}}, addrs)
+
+	_, ok = r.searchTargets(now, "eth2", "udp4")
+	assert.False(t, ok)
+	_, ok = r.searchTargets(now.Add(time.Minute), "eth1", "udp4")
+	assert.False(t, ok)
+
+	observeNotify(r, now, "eth1", "192.168.2.11", "ssdp:alive", "USN: uuid:5678::upnp:rootdevice")
+	_, ok = r.searchTargets(now, "eth1", "udp4")
+	assert.False(t, ok)
+}
+
+func TestRegistry_BootAndConfigIDs(t *testing.T) {
+	r := NewRegistry()
+	now := time.Now()
+	observe := func(nts string, headers ...string) []DeviceEvent {
+		data := "NOTIFY * HTTP/1.1\r\nNT: upnp:rootdevice\r\nNTS: " + nts + "\r\nUSN: uuid:1234::upnp:rootdevice\r\n"
+		for _, h := range headers {
+			data += h + "\r\n"
+		}
+		m := Message{Network: "udp4", IfName: "eth1", SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.2.10"), Port: 1900}}
+		p, _ := ParsePacket([]byte(data + "\r\n"))
+		return r.Observe(now, m, p)
+	}
+	kinds := func(events []DeviceEvent) []DeviceEventKind {
+		var k []DeviceEventKind
+		for _, e := range events {
+			k = append(k, e.Kind)
+		}
+		return k
+	}
+
+	assert.Equal(t, []DeviceEventKind{DeviceDiscovered}, kinds(observe("ssdp:alive", "BOOTID.UPNP.ORG: 5", "CONFIGID.UPNP.ORG: 1")))
+	assert.Empty(t, observe("ssdp:alive", "BOOTID.UPNP.ORG: 5", "CONFIGID.UPNP.ORG: 1"))
+
+	events := observe("ssdp:update", "BOOTID.UPNP.ORG: 5", "NEXTBOOTID.UPNP.ORG: 6", "CONFIGID.UPNP.ORG: 1")
+	assert.Equal(t, []DeviceEventKind{DeviceUpdated}, kinds(events))
+	assert.Equal(t, 6, events[0].BootID)
+	assert.Empty(t, observe("ssdp:alive", "BOOTID.UPNP.ORG: 6", "CONFIGID.UPNP.ORG: 1"))
+
+	events = observe("ssdp:alive", "BOOTID.UPNP.ORG: 2", "CONFIGID.UPNP.ORG: 3")
+	assert.Equal(t, []DeviceEventKind{DeviceRestarted, DeviceConfigChanged}, kinds(events))
+	assert.Equal(t, DeviceEvent{Time: now, Kind: DeviceConfigChanged, BootID: 2, ConfigID: 3}, events[1])
+
+	d := r.Devices(now)[0]
+	assert.Equal(t, 2, d.BootID)
+	assert.Equal(t, 3, d.ConfigID)
+	assert.Equal(t, []DeviceEventKind{DeviceDiscovered, DeviceUpdated, DeviceRestarted, DeviceConfigChanged}, kinds(d.History))
+}
+
+func TestRegistry_MaxDevices(t *testing.T) {
+	r := NewRegistry()
+	r.maxDevices = 2
+	now := time.Now()
+
+	for _, uuid := range []string{"uuid:1", "uuid:2", "uuid:3"} {
+		observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", "USN: "+uuid+"::upnp:rootdevice")
+	}
+	devices := r.Devices(now)
+	assert.Len(t, devices, 2)
+	assert.Equal(t, "uuid:1", devices[0].UUID)
+	assert.Equal(t, "uuid:2", devices[1].UUID)
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:byebye", "USN: uuid:1::upnp:rootdevice")
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", "USN: uuid:3::upnp:rootdevice")
+	assert.Equal(t, "uuid:3", r.Devices(now)[1].UUID)
+}
+
+func TestRegistry_MaxAge(t *testing.T) {
+	r := NewRegistry()
+	now := time.Now()
+
+	observeNotify(r, now, "eth1", "192.168.2.10", "ssdp:alive", "CACHE-CONTROL: max-age=2000000000")
+
+	assert.Equal(t, now.Add(24*time.Hour), r.Devices(now)[0].Expires)
+}
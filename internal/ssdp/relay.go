@@ -1,73 +1,278 @@
 package ssdp
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
 	"net"
 	"runtime"
-	"strconv"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/edutko/go-forward-ssdp/internal/netutil"
 )
 
 type Relay struct {
-	listeners             []Listener
+	protocols             []Protocol
+	listeners             []receiver
 	senders               []Sender
 	throttleCheckInterval time.Duration
 	throttlePacketLimit   uint64
+	heartbeatInterval     time.Duration
+	heartbeat             func(Stats)
+	stats                 map[string]*relayStats
+	recentBroadcasts      *recentPackets
+	registry              *Registry
+	searchProxy           bool
+	searchPort            int
+	interfaces            []net.Interface
+	done                  chan struct{}
+	closeOnce             *sync.Once
+}
+
+type receiver interface {
+	Listen(messages chan<- Message, errs chan<- error, wg *sync.WaitGroup)
+	Close() error
+}
+
+// Stats holds packet counts since the relay was created.
+type Stats struct {
+	Received uint64 `json:"received"`
+	Relayed  uint64 `json:"relayed"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+type relayStats struct {
+	received atomic.Uint64
+	relayed  atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+func (s *relayStats) snapshot() Stats {
+	return Stats{
+		Received: s.received.Load(),
+		Relayed:  s.relayed.Load(),
+		Dropped:  s.dropped.Load(),
+	}
+}
+
+type RelayOption func(r *Relay) error
+
+// WithProtocols sets the protocols to relay. By default, only SSDP is relayed. Each broadcast
+// port may be relayed by only one protocol, since a packet relayed by two would be subject only to
+// the more permissive of their policies.
+func WithProtocols(protocols ...Protocol) RelayOption {
+	return func(r *Relay) error {
+		names := make(map[string]bool)
+		ports := make(map[int]string)
+		for _, p := range protocols {
+			if p.Name == "" || names[p.Name] {
+				return fmt.Errorf("invalid or duplicate protocol name: %q", p.Name)
+			}
+			if len(p.Groups) == 0 && len(p.Ports) == 0 {
+				return fmt.Errorf("no multicast groups or broadcast ports for protocol %s", p.Name)
+			}
+			for _, port := range p.Ports {
+				if other, found := ports[port]; found {
+					return fmt.Errorf("broadcast port %d is relayed by both %s and %s", port, other, p.Name)
+				}
+				ports[port] = p.Name
+			}
+			names[p.Name] = true
+		}
+		r.protocols = protocols
+		return nil
+	}
+}
+
+// WithHeartbeat calls fn with the current statistics every interval from the goroutine that
+// processes packets, so fn is only called while the relay is making progress.
+func WithHeartbeat(interval time.Duration, fn func(Stats)) RelayOption {
+	return func(r *Relay) error {
+		if interval <= 0 {
+			return fmt.Errorf("invalid heartbeat interval: %s", interval)
+		}
+		r.heartbeatInterval = interval
+		r.heartbeat = fn
+		return nil
+	}
+}
+
+// WithSearchProxy relays SSDP M-SEARCH requests as unicast searches to the devices known on each
+// outgoing interface instead of multicasting them. Interfaces where no devices are known yet, or
+// where a device does not accept unicast searches, still receive a multicast search.
+func WithSearchProxy() RelayOption {
+	return func(r *Relay) error {
+		r.searchProxy = true
+		return nil
+	}
+}
+
+// WithUnicastSearch listens for unicast M-SEARCH requests sent to port on the addresses of the
+// incoming interfaces and relays them as though they had been multicast.
+func WithUnicastSearch(port int) RelayOption {
+	return func(r *Relay) error {
+		if port <= 0 || port > 65535 {
+			return fmt.Errorf("invalid search port: %d", port)
+		}
+		r.searchPort = port
+		return nil
+	}
+}
+
+// WithDescriptions fetches the description document of each device that advertises itself, so
+// that devices can be identified by name in logs and the admin API. Descriptions are only fetched
+// from the address the advertisement came from, and are fetched again when the device's LOCATION
+// or CONFIGID changes.
+func WithDescriptions(timeout time.Duration, maxSize int64) RelayOption {
+	return func(r *Relay) error {
+		if timeout <= 0 || maxSize <= 0 {
+			return fmt.Errorf("invalid description timeout or size limit: %s, %d", timeout, maxSize)
+		}
+		r.registry.fetch = newDescriptionFetcher(timeout, maxSize).fetch
+		return nil
+	}
 }
 
-func NewRelay(in []net.Interface, out []net.Interface) (Relay, error) {
+func NewRelay(in []net.Interface, out []net.Interface, opts ...RelayOption) (Relay, error) {
 	r := Relay{
-		listeners:             []Listener{},
+		listeners:             []receiver{},
 		senders:               []Sender{},
 		throttleCheckInterval: 500 * time.Millisecond,
 		throttlePacketLimit:   250,
+		stats:                 make(map[string]*relayStats),
+		recentBroadcasts:      newRecentPackets(500 * time.Millisecond),
+		registry:              NewRegistry(),
+		done:                  make(chan struct{}),
+		closeOnce:             &sync.Once{},
+	}
+
+	for _, opt := range opts {
+		if err := opt(&r); err != nil {
+			return Relay{}, fmt.Errorf("configuring relay: %w", err)
+		}
 	}
 
-	var e error
-	for _, ifi := range in {
-		l, err := NewListener(ifi, "udp4")
+	if len(r.protocols) == 0 {
+		policy, err := NewSSDPPolicy()
 		if err != nil {
-			e = err
-			break
+			return Relay{}, err
+		}
+		r.protocols = []Protocol{SSDP(policy)}
+	}
+
+	networks := make(map[string]bool)
+	for _, p := range r.protocols {
+		r.stats[p.Name] = &relayStats{}
+		for _, g := range p.Groups {
+			networks[g.Network()] = true
 		}
-		r.listeners = append(r.listeners, l)
+		if len(p.Ports) > 0 {
+			networks["udp4"] = true
+		}
+	}
+
+	for _, ifi := range slices.Concat(in, out) {
+		if !slices.ContainsFunc(r.interfaces, func(i net.Interface) bool { return i.Index == ifi.Index }) {
+			r.interfaces = append(r.interfaces, ifi)
+		}
+	}
 
-		// Go does not currently support listening for UDPv6 multicast on Windows
-		if runtime.GOOS != "windows" {
-			l, err = NewListener(ifi, "udp6")
+	e := r.openListeners(in)
+	if e == nil {
+		logMembershipProblems(checkMemberships(in, r.protocols))
+		e = r.openSenders(out, networks)
+	}
+	if e != nil {
+		_ = r.close()
+	}
+
+	return r, e
+}
+
+func (r *Relay) openListeners(ifs []net.Interface) error {
+	for _, p := range r.protocols {
+		for _, port := range p.Ports {
+			l, err := NewBroadcastListener(ifs, p.Name, port)
 			if err != nil {
-				e = err
-				break
+				return fmt.Errorf("listening for %s broadcasts on port %d: %w", p.Name, port, err)
 			}
 			r.listeners = append(r.listeners, l)
 		}
 	}
 
-	for _, ifi := range out {
-		s, err := NewSender(ifi, "udp4")
-		if err != nil {
-			e = err
-			break
+	for _, ifi := range ifs {
+		for _, p := range r.protocols {
+			ls, err := NewProtocolListeners(ifi, p)
+			if err != nil {
+				return fmt.Errorf("listening for %s on %s: %w", p.Name, ifi.Name, err)
+			}
+			for _, l := range ls {
+				r.listeners = append(r.listeners, l)
+			}
 		}
-		r.senders = append(r.senders, s)
+	}
+
+	if r.searchPort > 0 {
+		return r.openSearchListeners(ifs)
+	}
+	return nil
+}
+
+func (r *Relay) openSearchListeners(ifs []net.Interface) error {
+	i := slices.IndexFunc(r.protocols, func(p Protocol) bool { return p.Name == "ssdp" })
+	if i < 0 {
+		return fmt.Errorf("unicast search requires the ssdp protocol")
+	}
+	groups := r.protocols[i].Groups
 
-		// We're not listening on IPv6 on Windows, so no need to send
-		if runtime.GOOS != "windows" {
-			s, err := NewSender(ifi, "udp6")
+	for _, ifi := range ifs {
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			return fmt.Errorf("listing addresses for %s: %w", ifi.Name, err)
+		}
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			network := "udp6"
+			if ipNet.IP.To4() != nil {
+				network = "udp4"
+			} else if runtime.GOOS == "windows" {
+				continue
+			}
+			j := slices.IndexFunc(groups, func(g Group) bool { return g.Network() == network && g.onInterface(ifi.Name) })
+			if j < 0 {
+				continue
+			}
+			l, err := NewSearchListener(ifi, ipNet.IP, r.searchPort, groups[j])
 			if err != nil {
-				e = err
-				break
+				return fmt.Errorf("listening for unicast searches on %s (%s): %w", ifi.Name, ipNet.IP, err)
 			}
-			r.senders = append(r.senders, s)
+			r.listeners = append(r.listeners, l)
 		}
 	}
+	return nil
+}
 
-	if e != nil {
-		_ = r.close()
+func (r *Relay) openSenders(ifs []net.Interface, networks map[string]bool) error {
+	for _, ifi := range ifs {
+		for _, network := range []string{"udp4", "udp6"} {
+			// We're not listening on IPv6 on Windows, so no need to send
+			if !networks[network] || network == "udp6" && runtime.GOOS == "windows" {
+				continue
+			}
+			s, err := NewSender(ifi, network)
+			if err != nil {
+				return err
+			}
+			r.senders = append(r.senders, s)
+		}
 	}
-
-	return r, e
+	return nil
 }
 
 func (r Relay) Serve() error {
@@ -76,52 +281,192 @@ func (r Relay) Serve() error {
 	errs := make(chan error, len(r.listeners))
 
 	for _, l := range r.listeners {
+		wg.Add(1)
 		go l.Listen(msgs, errs, &wg)
 	}
 
 	err := r.serve(msgs, errs)
 	_ = r.close()
+
+	// Keep listeners from blocking on a full channel while they shut down
+	go func() {
+		for range msgs {
+		}
+	}()
 	wg.Wait()
+	close(msgs)
 
 	return err
 }
 
+// Close stops the relay. Serve returns nil once the relay has been closed.
+func (r Relay) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+	})
+	return nil
+}
+
+// Stats returns packet counts for all protocols combined.
+func (r Relay) Stats() Stats {
+	var total Stats
+	for _, s := range r.stats {
+		ps := s.snapshot()
+		total.Received += ps.Received
+		total.Relayed += ps.Relayed
+		total.Dropped += ps.Dropped
+	}
+	return total
+}
+
+// Devices returns the UPnP devices currently advertising themselves to the relay.
+func (r Relay) Devices() []Device {
+	return r.registry.Devices(time.Now())
+}
+
+// DeviceStats returns packet counts for each UPnP device currently advertising itself to the
+// relay.
+func (r Relay) DeviceStats() []DeviceStats {
+	return r.registry.DeviceStats(time.Now())
+}
+
+// Interfaces describes the interfaces the relay listens or sends on.
+func (r Relay) Interfaces() []netutil.InterfaceInfo {
+	infos := []netutil.InterfaceInfo{}
+	for _, ifi := range r.interfaces {
+		infos = append(infos, netutil.Describe(ifi))
+	}
+	return infos
+}
+
+// StatsByProtocol returns packet counts for each protocol, keyed by protocol name.
+func (r Relay) StatsByProtocol() map[string]Stats {
+	stats := make(map[string]Stats, len(r.stats))
+	for name, s := range r.stats {
+		stats[name] = s.snapshot()
+	}
+	return stats
+}
+
 func (r Relay) serve(messages <-chan Message, errs <-chan error) error {
-	var packetCount uint64
+	protocols := make(map[string]Protocol, len(r.protocols))
+	for _, p := range r.protocols {
+		protocols[p.Name] = p
+	}
+
+	packetCounts := make(map[string]uint64, len(r.protocols))
 	tick := time.Tick(r.throttleCheckInterval)
+
+	var heartbeat <-chan time.Time
+	if r.heartbeat != nil {
+		t := time.NewTicker(r.heartbeatInterval)
+		defer t.Stop()
+		heartbeat = t.C
+	}
+
 	for {
 		select {
 		case <-tick:
-			packetCount = 0
+			clear(packetCounts)
+		case <-heartbeat:
+			r.heartbeat(r.Stats())
 		case m := <-messages:
-			packetCount++
-			if packetCount > r.throttlePacketLimit {
-				log.Println("warning: too many packets per second; dropping packet")
+			p := protocols[m.Protocol]
+			stats := r.stats[m.Protocol]
+			stats.received.Add(1)
+
+			limit := p.PacketLimit
+			if limit == 0 {
+				limit = r.throttlePacketLimit
+			}
+			packetCounts[m.Protocol]++
+			if packetCounts[m.Protocol] > limit {
+				stats.dropped.Add(1)
+				slog.Warn("too many packets per second; dropping packet", m.logAttrs()...)
 			} else {
-				r.relay(m)
+				r.relay(p, stats, m)
 			}
 		case e := <-errs:
 			return e
+		case <-r.done:
+			return nil
 		}
 	}
 }
 
-func (r Relay) relay(m Message) {
-	host, ps, err := net.SplitHostPort(m.SourceIP.String())
-	if err != nil {
-		log.Printf("error splitting host and port: %s\n", err.Error())
+func (r Relay) relay(p Protocol, stats *relayStats, m Message) {
+	now := time.Now()
+	attrs := m.logAttrs()
+	if m.Group.isBroadcast() && r.recentBroadcasts.check(now, m) {
+		stats.dropped.Add(1)
+		slog.Debug("dropping packet", append(attrs, "reason", "duplicate broadcast")...)
+		return
 	}
-	port, err := strconv.Atoi(ps)
-	if err != nil {
-		log.Printf("error parsing port: %s\n", err.Error())
+
+	var pkt Packet
+	relayed := false
+	if p.Name == "ssdp" {
+		m = m.withPacket()
+		pkt, _ = m.packet()
+		defer func() { r.registry.count(m.Network, pkt.USN(), relayed) }()
+		events := r.registry.Observe(now, m, pkt)
+		if name := r.registry.friendlyName(m.Network, pkt.USN()); name != "" {
+			attrs = append(attrs, "device", name)
+		}
+		for _, e := range events {
+			level := slog.LevelInfo
+			if e.Kind == DeviceDiscovered {
+				level = slog.LevelDebug
+			}
+			slog.Log(context.Background(), level, "device "+string(e.Kind), append(attrs,
+				"usn", pkt.USN(), "bootid", e.BootID, "configid", e.ConfigID)...)
+		}
+	}
+
+	var targets []string
+	if p.Policy != nil {
+		v := p.Policy.Inspect(m)
+		attrs = append(attrs, v.Attrs...)
+		targets = v.Targets
+		if !v.Relay {
+			stats.dropped.Add(1)
+			slog.Log(context.Background(), v.Level, "dropping packet", append(attrs, "reason", v.Reason)...)
+			return
+		}
+	}
+
+	srcIP, srcPort := m.Source()
+	if srcIP == nil {
+		stats.dropped.Add(1)
+		slog.Error("error parsing source address", attrs...)
+		return
 	}
+
+	slog.Debug("relaying packet", attrs...)
+	stats.relayed.Add(1)
+	relayed = true
 	for _, s := range r.senders {
-		if s.network == m.Network && s.ifi.Name != m.IfName {
-			_, err := s.Send(m.Data, net.ParseIP(host), port)
-			if err != nil {
-				log.Printf("error relaying packet from %s: %s\n", m.SourceIP.String(), err.Error())
+		if s.network != m.Network || s.ifi.Name == m.IfName || !m.Group.onInterface(s.ifi.Name) {
+			continue
+		}
+		if len(targets) > 0 && !slices.Contains(targets, s.ifi.Name) {
+			continue
+		}
+		if r.searchProxy && pkt.Type == SearchMessage {
+			if addrs, ok := r.registry.searchTargets(now, s.ifi.Name, s.network); ok {
+				for _, a := range addrs {
+					_, err := s.SendTo(withHost(m.Data, a), srcIP, srcPort, Group{Addr: a, HopLimit: 1})
+					if err != nil {
+						slog.Error("error relaying packet", append(attrs, "destination", a.String(), "error", err)...)
+					}
+				}
+				continue
 			}
 		}
+		_, err := s.SendTo(m.Data, srcIP, srcPort, m.Group)
+		if err != nil {
+			slog.Error("error relaying packet", append(attrs, "destination", s.ifi.Name, "error", err)...)
+		}
 	}
 }
 
@@ -129,5 +474,8 @@ func (r Relay) close() error {
 	for _, l := range r.listeners {
 		_ = l.Close()
 	}
+	for _, s := range r.senders {
+		_ = s.Close()
+	}
 	return nil
 }
@@ -0,0 +1,126 @@
+package ssdp
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithProtocols_OverlappingBroadcastPorts(t *testing.T) {
+	var r Relay
+	err := WithProtocols(Broadcast("broadcast", 5000, 9), Broadcast("wol", 7, 9))(&r)
+	assert.EqualError(t, err, "broadcast port 9 is relayed by both broadcast and wol")
+
+	err = WithProtocols(Broadcast("broadcast", 5000), Broadcast("wol", 7, 9))(&r)
+	assert.Nil(t, err)
+	assert.Len(t, r.protocols, 2)
+}
+
+type blockingReceiver struct {
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newBlockingReceiver() *blockingReceiver {
+	return &blockingReceiver{closed: make(chan struct{})}
+}
+
+func (b *blockingReceiver) Listen(_ chan<- Message, _ chan<- error, wg *sync.WaitGroup) {
+	defer wg.Done()
+	<-b.closed
+}
+
+func (b *blockingReceiver) Close() error {
+	b.closeOnce.Do(func() { close(b.closed) })
+	return nil
+}
+
+func TestRelay_Heartbeat(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	beats := make(chan Stats)
+	r, err := NewRelay(nil, nil, WithHeartbeat(interval, func(s Stats) { beats <- s }))
+	assert.Nil(t, err)
+
+	select {
+	case <-beats:
+		t.Fatal("heartbeat before serving")
+	case <-time.After(3 * interval):
+	}
+
+	msgs := make(chan Message)
+	errs := make(chan error)
+	served := make(chan error)
+	start := time.Now()
+	go func() { served <- r.serve(msgs, errs) }()
+
+	assert.Equal(t, Stats{}, <-beats)
+	assert.GreaterOrEqual(t, time.Since(start), interval)
+
+	// While the next heartbeat is blocked, the serve loop takes no packets.
+	time.Sleep(5 * interval)
+	m := Message{
+		Protocol: "ssdp",
+		Network:  "udp4",
+		IfName:   "eth0",
+		SourceIP: &net.UDPAddr{IP: net.IPv4(192, 168, 1, 10), Port: 1900},
+		Data:     []byte("garbage"),
+	}
+	select {
+	case msgs <- m:
+		t.Fatal("packet processed during heartbeat")
+	case <-time.After(3 * interval):
+	}
+	for sent := false; !sent; {
+		select {
+		case msgs <- m:
+			sent = true
+		case <-beats:
+		}
+	}
+	assert.Equal(t, uint64(1), (<-beats).Received)
+
+	go func() { <-beats }()
+	assert.Nil(t, r.Close())
+	assert.Nil(t, <-served)
+}
+
+func TestWithHeartbeat_InvalidInterval(t *testing.T) {
+	_, err := NewRelay(nil, nil, WithHeartbeat(0, func(Stats) {}))
+	assert.EqualError(t, err, "configuring relay: invalid heartbeat interval: 0s")
+}
+
+func TestRelay_Close(t *testing.T) {
+	r, err := NewRelay(nil, nil)
+	assert.Nil(t, err)
+	l := newBlockingReceiver()
+	r.listeners = append(r.listeners, l)
+
+	served := make(chan error)
+	go func() { served <- r.Serve() }()
+
+	assert.Nil(t, r.Close())
+	assert.Nil(t, r.Close())
+	select {
+	case err := <-served:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+	select {
+	case <-l.closed:
+	default:
+		t.Fatal("listener not closed")
+	}
+}
+
+func TestRelay_ServeListenerError(t *testing.T) {
+	r, err := NewRelay(nil, nil)
+	assert.Nil(t, err)
+	errs := make(chan error, 1)
+	errs <- net.ErrClosed
+
+	assert.ErrorIs(t, r.serve(make(chan Message), errs), net.ErrClosed)
+}
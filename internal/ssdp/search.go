@@ -0,0 +1,95 @@
+package ssdp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// SearchListener receives unicast M-SEARCH requests addressed to one of the relay's own addresses,
+// so that control points that search via the router can be relayed like multicast searches.
+// Other messages are ignored.
+type SearchListener struct {
+	group Group
+	conn  net.PacketConn
+	ifi   net.Interface
+	buf   []byte
+}
+
+// NewSearchListener listens on ip:port, which should be an address of ifi. Searches received are
+// labeled as having been sent to group.
+func NewSearchListener(ifi net.Interface, ip net.IP, port int, group Group) (SearchListener, error) {
+	addr := &net.UDPAddr{IP: ip, Port: port}
+	if ip.IsLinkLocalUnicast() {
+		addr.Zone = ifi.Name
+	}
+
+	lc := net.ListenConfig{Control: reuseAddr}
+	conn, err := lc.ListenPacket(context.Background(), group.Network(), addr.String())
+	if err != nil {
+		return SearchListener{}, err
+	}
+
+	return SearchListener{group, conn, ifi, make([]byte, 65535)}, nil
+}
+
+// Listen reads packets until the connection is closed or fails, then sends the error to errs and
+// calls wg.Done.
+func (l SearchListener) Listen(messages chan<- Message, errs chan<- error, wg *sync.WaitGroup) {
+	for {
+		n, addr, err := l.conn.ReadFrom(l.buf)
+		if err != nil {
+			errs <- err
+			wg.Done()
+			return
+		}
+		p, err := ParsePacket(l.buf[:n])
+		if err != nil || p.Type != SearchMessage {
+			continue
+		}
+
+		msg := make([]byte, n)
+		copy(msg, l.buf)
+		messages <- Message{
+			Protocol: "ssdp",
+			Group:    l.group,
+			Network:  l.group.Network(),
+			IfName:   l.ifi.Name,
+			SourceIP: addr,
+			Data:     msg,
+			parsed:   &parsedPacket{packet: p},
+		}
+	}
+}
+
+func (l SearchListener) Close() error {
+	return l.conn.Close()
+}
+
+// NewSearchRequest returns an M-SEARCH request for st, to be multicast to group. Devices wait up to
+// mx seconds before responding.
+func NewSearchRequest(group Group, st string, mx int) []byte {
+	return []byte(fmt.Sprintf("M-SEARCH * HTTP/1.1\r\nHOST: %s\r\nMAN: \"ssdp:discover\"\r\nMX: %d\r\nST: %s\r\n\r\n",
+		group, mx, st))
+}
+
+// withHost returns a copy of an SSDP message with its HOST header set to addr, as required for
+// unicast M-SEARCH requests.
+func withHost(data []byte, addr *net.UDPAddr) []byte {
+	host := net.JoinHostPort(addr.IP.String(), strconv.Itoa(addr.Port))
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines[1:] {
+		header := bytes.TrimRight(line, "\r\n")
+		if len(header) == 0 {
+			break
+		}
+		name, _, found := bytes.Cut(header, []byte(":"))
+		if found && bytes.EqualFold(bytes.TrimSpace(name), []byte("HOST")) {
+			lines[i+1] = append([]byte("HOST: "+host), line[len(header):]...)
+		}
+	}
+	return bytes.Join(lines, nil)
+}
@@ -0,0 +1,28 @@
+package ssdp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSearchRequest(t *testing.T) {
+	p, err := ParsePacket(NewSearchRequest(SSDP(nil).Groups[0], "roku:ecp", 3))
+
+	assert.Nil(t, err)
+	assert.Equal(t, SearchMessage, p.Type)
+	assert.Equal(t, "239.255.255.250:1900", p.Get("HOST"))
+	assert.Equal(t, `"ssdp:discover"`, p.Get("MAN"))
+	assert.Equal(t, "3", p.Get("MX"))
+	assert.Equal(t, "roku:ecp", p.ST())
+}
+
+func TestWithHost(t *testing.T) {
+	data := []byte("M-SEARCH * HTTP/1.1\r\nHost: 239.255.255.250:1900\r\nST: ssdp:all\r\n\r\n")
+
+	actual := withHost(data, &net.UDPAddr{IP: net.ParseIP("192.168.2.10"), Port: 49152})
+
+	assert.Equal(t, "M-SEARCH * HTTP/1.1\r\nHOST: 192.168.2.10:49152\r\nST: ssdp:all\r\n\r\n", string(actual))
+	assert.Equal(t, "M-SEARCH * HTTP/1.1\r\nHost: 239.255.255.250:1900\r\nST: ssdp:all\r\n\r\n", string(data))
+}
@@ -0,0 +1,29 @@
+package ssdp
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// restrictMulticast stops conn from receiving traffic for groups joined by other sockets. Linux
+// otherwise delivers packets for a group joined on any interface to every socket bound to the
+// port, so a listener would report packets from other interfaces as its own.
+func restrictMulticast(conn *net.UDPConn, network string) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		if network == "udp6" {
+			serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_ALL, 0)
+		} else {
+			serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MULTICAST_ALL, 0)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
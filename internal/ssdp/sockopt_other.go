@@ -0,0 +1,9 @@
+//go:build !linux
+
+package ssdp
+
+import "net"
+
+func restrictMulticast(_ *net.UDPConn, _ string) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package ssdp
+
+import (
+	"net"
+	"syscall"
+)
+
+func setBroadcast(conn *net.IPConn) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	err = rc.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
+
+// reuseAddr is a net.ListenConfig Control function that sets SO_REUSEADDR, so that a socket can be
+// bound to a specific address on a port the multicast listeners already hold.
+func reuseAddr(_, _ string, rc syscall.RawConn) error {
+	var serr error
+	err := rc.Control(func(fd uintptr) {
+		serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return serr
+}
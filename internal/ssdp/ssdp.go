@@ -3,6 +3,8 @@ package ssdp
 import (
 	"fmt"
 	"net"
+	"runtime"
+	"strconv"
 	"sync"
 
 	"github.com/google/gopacket"
@@ -12,40 +14,115 @@ import (
 )
 
 var (
-	ipv4UDPAddr = &net.UDPAddr{IP: net.ParseIP("239.255.255.250"), Port: 1900}
+	ipv4UDPAddr          = &net.UDPAddr{IP: net.ParseIP("239.255.255.250"), Port: 1900}
 	ipv6LinkLocalUDPAddr = &net.UDPAddr{IP: net.ParseIP("ff02::c"), Port: 1900}
+	ipv6SiteLocalUDPAddr = &net.UDPAddr{IP: net.ParseIP("ff05::c"), Port: 1900}
+	ipv6OrgLocalUDPAddr  = &net.UDPAddr{IP: net.ParseIP("ff08::c"), Port: 1900}
 )
 
 type Message struct {
+	Protocol string
+	Group    Group
 	Network  string
 	IfName   string
 	SourceIP net.Addr
 	Data     []byte
+
+	// parsed holds the result of parsing Data as an SSDP packet, once it has been parsed.
+	parsed *parsedPacket
 }
 
-type Listener struct {
-	network string
-	conn    *net.UDPConn
-	ifi     net.Interface
-	buf     []byte
+type parsedPacket struct {
+	packet Packet
+	err    error
+}
+
+// withPacket returns a copy of m that carries Data parsed as an SSDP packet, so that it is only
+// parsed once however many times packet is called.
+func (m Message) withPacket() Message {
+	if m.parsed == nil {
+		p, err := ParsePacket(m.Data)
+		m.parsed = &parsedPacket{p, err}
+	}
+	return m
+}
+
+// packet parses Data as an SSDP packet.
+func (m Message) packet() (Packet, error) {
+	if m.parsed != nil {
+		return m.parsed.packet, m.parsed.err
+	}
+	return ParsePacket(m.Data)
 }
 
-func NewListener(ifi net.Interface, network string) (Listener, error) {
-	addr, err := getMulticastUDPAddr(network)
+// Source returns the IP address and port the message was sent from.
+func (m Message) Source() (net.IP, int) {
+	if a, ok := m.SourceIP.(*net.UDPAddr); ok {
+		return a.IP, a.Port
+	}
+
+	host, ps, err := net.SplitHostPort(m.SourceIP.String())
 	if err != nil {
-		return Listener{}, fmt.Errorf("resolving UDP address: %w", err)
+		return nil, 0
 	}
+	port, _ := strconv.Atoi(ps)
+	return net.ParseIP(host), port
+}
 
-	conn, err := net.ListenMulticastUDP(network, &ifi, addr)
+func (m Message) logAttrs() []any {
+	attrs := []any{"interface", m.IfName, "network", m.Network, "source", m.SourceIP.String()}
+	if m.Protocol != "" {
+		attrs = append(attrs, "protocol", m.Protocol)
+	}
+	return attrs
+}
+
+type Listener struct {
+	protocol string
+	group    Group
+	conn     *net.UDPConn
+	ifi      net.Interface
+	buf      []byte
+}
+
+// NewProtocolListeners joins each of p's groups that applies to ifi. Messages received are
+// labeled with p's name.
+func NewProtocolListeners(ifi net.Interface, p Protocol) ([]Listener, error) {
+	var listeners []Listener
+	for _, g := range p.Groups {
+		// Go does not currently support listening for UDPv6 multicast on Windows
+		if g.Network() == "udp6" && runtime.GOOS == "windows" || !g.onInterface(ifi.Name) {
+			continue
+		}
+		l, err := NewGroupListener(ifi, p.Name, g)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("joining %s: %w", g, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// NewGroupListener joins group on ifi. Messages received are labeled with protocol.
+func NewGroupListener(ifi net.Interface, protocol string, group Group) (Listener, error) {
+	conn, err := net.ListenMulticastUDP(group.Network(), &ifi, group.Addr)
 	if err != nil {
 		return Listener{}, err
 	}
+	if err := restrictMulticast(conn, group.Network()); err != nil {
+		_ = conn.Close()
+		return Listener{}, fmt.Errorf("restricting multicast delivery: %w", err)
+	}
 
-	return Listener{network, conn, ifi, make([]byte, 65535)}, nil
+	return Listener{protocol, group, conn, ifi, make([]byte, 65535)}, nil
 }
 
+// Listen reads packets until the connection is closed or fails, then sends the error to errs and
+// calls wg.Done.
 func (l Listener) Listen(messages chan<- Message, errs chan<- error, wg *sync.WaitGroup) {
-	wg.Add(1)
 	for {
 		n, addr, err := l.conn.ReadFrom(l.buf)
 		if err != nil {
@@ -56,7 +133,9 @@ func (l Listener) Listen(messages chan<- Message, errs chan<- error, wg *sync.Wa
 		msg := make([]byte, n)
 		copy(msg, l.buf)
 		messages <- Message{
-			Network:  l.network,
+			Protocol: l.protocol,
+			Group:    l.group,
+			Network:  l.group.Network(),
 			IfName:   l.ifi.Name,
 			SourceIP: addr,
 			Data:     msg,
@@ -68,115 +147,182 @@ func (l Listener) Close() error {
 	return l.conn.Close()
 }
 
+// Sender sends packets with arbitrary source addresses out of a single interface. It holds a raw
+// socket open, so it must be created while the process still has the privileges to do so.
 type Sender struct {
 	network string
 	ifi     net.Interface
+	conn    *net.IPConn
+	raw4    *ipv4.RawConn
+	pc6     *ipv6.PacketConn
 }
 
 func NewSender(ifi net.Interface, network string) (Sender, error) {
-	return Sender{network: network, ifi: ifi}, nil
+	s := Sender{network: network, ifi: ifi}
+
+	var err error
+	switch network {
+	case "udp4":
+		err = s.openIPv4()
+	case "udp6":
+		err = s.openIPv6()
+	default:
+		err = fmt.Errorf("unsupported network: %s", network)
+	}
+	if err != nil {
+		if s.conn != nil {
+			_ = s.conn.Close()
+		}
+		return Sender{}, err
+	}
+
+	return s, nil
 }
 
+// Send sends data to the SSDP multicast group.
 func (s Sender) Send(data []byte, srcIP net.IP, srcPort int) (int, error) {
+	for _, g := range SSDP(nil).Groups {
+		if g.Network() == s.network {
+			return s.SendTo(data, srcIP, srcPort, g)
+		}
+	}
+	return 0, fmt.Errorf("unsupported network: %s", s.network)
+}
+
+// SendTo sends data to group, which must belong to the sender's network.
+func (s Sender) SendTo(data []byte, srcIP net.IP, srcPort int, group Group) (int, error) {
+	if group.Network() != s.network {
+		return 0, fmt.Errorf("cannot send to %s group %s on %s", group.Network(), group, s.network)
+	}
+
 	switch s.network {
 	case "udp4":
-		return s.sendIPv4(data, srcIP, srcPort)
+		return s.sendIPv4(data, srcIP, srcPort, group)
 
 	case "udp6":
-		return s.sendIPv6(data, srcIP, srcPort)
+		return s.sendIPv6(data, srcIP, srcPort, group)
 
 	default:
 		return 0, fmt.Errorf("unsupported network: %s", s.network)
 	}
 }
 
-func getMulticastUDPAddr(network string) (*net.UDPAddr, error) {
-	switch network {
-	case "udp4":
-		return ipv4UDPAddr, nil
-	case "udp6":
-		return ipv6LinkLocalUDPAddr, nil
-	default:
-		return nil, fmt.Errorf("unsupported network: %s", network)
-	}
+func (s Sender) Close() error {
+	return s.conn.Close()
 }
 
-func (s Sender) sendIPv4(data []byte, srcIP net.IP, srcPort int) (int, error) {
-	iph, payload, err := buildIPv4Packet(srcIP, srcPort, data)
+func (s *Sender) openIPv4() error {
+	var err error
+	s.conn, err = net.ListenIP("ip4:udp", nil)
 	if err != nil {
-		return 0, fmt.Errorf("building packet: %w", err)
+		return fmt.Errorf("connecting: %w", err)
 	}
 
-	conn, err := net.ListenIP("ip4:udp", nil)
+	s.raw4, err = ipv4.NewRawConn(s.conn)
 	if err != nil {
-		return 0, fmt.Errorf("connecting: %w", err)
+		return fmt.Errorf("creating raw connection: %w", err)
 	}
-	defer conn.Close()
 
-	rConn, err := ipv4.NewRawConn(conn)
+	err = s.raw4.SetMulticastInterface(&s.ifi)
 	if err != nil {
-		return 0, fmt.Errorf("creating raw connection: %w", err)
+		return fmt.Errorf("setting multicast interface: %w", err)
 	}
-	defer rConn.Close()
-
-	err = rConn.SetMulticastInterface(&s.ifi)
+	err = s.raw4.SetMulticastLoopback(false)
 	if err != nil {
-		return 0, fmt.Errorf("setting multicast interface: %w", err)
+		return fmt.Errorf("disabling multicast loopback: %w", err)
 	}
-	err = rConn.SetMulticastLoopback(false)
+	err = s.raw4.SetMulticastTTL(1)
 	if err != nil {
-		return 0, fmt.Errorf("disabling multicast loopback: %w", err)
+		return fmt.Errorf("setting multicast TTL: %w", err)
 	}
-	err = rConn.SetMulticastTTL(1)
+	err = setBroadcast(s.conn)
 	if err != nil {
-		return 0, fmt.Errorf("setting multicast TTL: %w", err)
+		return fmt.Errorf("enabling broadcast: %w", err)
 	}
 
-	return len(data), rConn.WriteTo(iph, payload, nil)
+	return nil
 }
 
-func (s Sender) sendIPv6(data []byte, srcIP net.IP, srcPort int) (int, error) {
-	packet, cm, err := buildIPv6Packet(srcIP, srcPort, data)
+func (s *Sender) openIPv6() error {
+	var err error
+	s.conn, err = net.ListenIP("ip6:udp", nil)
 	if err != nil {
-		return 0, fmt.Errorf("building packet: %w", err)
+		return fmt.Errorf("connecting: %w", err)
 	}
 
-	conn, err := net.ListenIP("ip6:udp", nil)
+	s.pc6 = ipv6.NewPacketConn(s.conn)
+
+	err = s.pc6.SetMulticastInterface(&s.ifi)
+	if err != nil {
+		return fmt.Errorf("setting multicast interface: %w", err)
+	}
+	err = s.pc6.SetMulticastLoopback(false)
 	if err != nil {
-		return 0, fmt.Errorf("connecting: %w", err)
+		return fmt.Errorf("disabling multicast loopback: %w", err)
 	}
-	defer conn.Close()
 
-	pConn := ipv6.NewPacketConn(conn)
-	defer pConn.Close()
+	return nil
+}
+
+func (s Sender) sendIPv4(data []byte, srcIP net.IP, srcPort int, group Group) (int, error) {
+	if group.isBroadcast() {
+		return s.sendIPv4Broadcast(data, srcIP, srcPort, group)
+	}
 
-	err = pConn.SetMulticastInterface(&s.ifi)
+	iph, payload, err := buildIPv4Packet(srcIP, srcPort, group.Addr, group.HopLimit, data)
 	if err != nil {
-		return 0, fmt.Errorf("setting multicast interface: %w", err)
+		return 0, fmt.Errorf("building packet: %w", err)
 	}
-	err = pConn.SetMulticastLoopback(false)
+
+	return len(data), s.raw4.WriteTo(iph, payload, nil)
+}
+
+// sendIPv4Broadcast sends data to the directed broadcast address of each IPv4 network on the
+// sender's interface.
+func (s Sender) sendIPv4Broadcast(data []byte, srcIP net.IP, srcPort int, group Group) (int, error) {
+	bcasts, err := broadcastAddrs(s.ifi)
 	if err != nil {
-		return 0, fmt.Errorf("disabling multicast loopback: %w", err)
+		return 0, fmt.Errorf("listing broadcast addresses: %w", err)
 	}
-	err = pConn.SetMulticastHopLimit(1)
+	if len(bcasts) == 0 {
+		return 0, fmt.Errorf("no IPv4 broadcast address on %s", s.ifi.Name)
+	}
+
+	for _, b := range bcasts {
+		dst := &net.UDPAddr{IP: b, Port: group.Addr.Port}
+		iph, payload, err := buildIPv4Packet(srcIP, srcPort, dst, group.HopLimit, data)
+		if err != nil {
+			return 0, fmt.Errorf("building packet: %w", err)
+		}
+		if err := s.raw4.WriteTo(iph, payload, nil); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+func (s Sender) sendIPv6(data []byte, srcIP net.IP, srcPort int, group Group) (int, error) {
+	packet, cm, err := buildIPv6Packet(srcIP, srcPort, group.Addr, group.HopLimit, data)
 	if err != nil {
-		return 0, fmt.Errorf("setting multicast hop limit: %w", err)
+		return 0, fmt.Errorf("building packet: %w", err)
 	}
+	cm.IfIndex = s.ifi.Index
 
-	return pConn.WriteTo(packet, cm, ipv6LinkLocalUDPAddr)
+	return s.pc6.WriteTo(packet, cm, &net.IPAddr{IP: group.Addr.IP})
 }
 
-func buildIPv4Packet(srcIP net.IP, srcPort int, data []byte) (*ipv4.Header, []byte, error) {
+func buildIPv4Packet(srcIP net.IP, srcPort int, dst *net.UDPAddr, ttl int, data []byte) (*ipv4.Header, []byte, error) {
 	ip4 := &layers.IPv4{
 		Version:  4,
-		TTL:      1,
+		TTL:      uint8(ttl),
 		Protocol: layers.IPProtocolUDP,
 		SrcIP:    srcIP,
-		DstIP:    ipv4UDPAddr.IP,
+		DstIP:    dst.IP,
 	}
 	udp := &layers.UDP{
 		SrcPort: layers.UDPPort(srcPort),
-		DstPort: layers.UDPPort(1900),
+		DstPort: layers.UDPPort(dst.Port),
 	}
 	err := udp.SetNetworkLayerForChecksum(ip4)
 	if err != nil {
@@ -198,28 +344,27 @@ func buildIPv4Packet(srcIP net.IP, srcPort int, data []byte) (*ipv4.Header, []by
 		Version:  ipv4.Version,
 		Len:      ipv4.HeaderLen,
 		TotalLen: ipv4.HeaderLen + len(payload),
-		TTL:      1,
+		TTL:      ttl,
 		Protocol: 17,
 		Src:      srcIP.To4(),
-		Dst:      ipv4UDPAddr.IP.To4(),
+		Dst:      dst.IP.To4(),
 	}
 
 	return iph, payload, nil
 }
 
-func buildIPv6Packet(srcIP net.IP, srcPort int, data []byte) ([]byte, *ipv6.ControlMessage, error) {
+func buildIPv6Packet(srcIP net.IP, srcPort int, dst *net.UDPAddr, hopLimit int, data []byte) ([]byte, *ipv6.ControlMessage, error) {
 	ip6 := &layers.IPv6{
 		SrcIP:      srcIP,
-		DstIP:      ipv6LinkLocalUDPAddr.IP,
+		DstIP:      dst.IP,
 		NextHeader: layers.IPProtocolUDP,
 		Version:    6,
-		HopLimit:   1,
+		HopLimit:   uint8(hopLimit),
 	}
-	ip6.LayerType()
 
 	udp := &layers.UDP{
 		SrcPort: layers.UDPPort(srcPort),
-		DstPort: layers.UDPPort(1900),
+		DstPort: layers.UDPPort(dst.Port),
 	}
 	err := udp.SetNetworkLayerForChecksum(ip6)
 	if err != nil {
@@ -238,9 +383,9 @@ func buildIPv6Packet(srcIP net.IP, srcPort int, data []byte) ([]byte, *ipv6.Cont
 	packet := buf.Bytes()
 
 	cm := &ipv6.ControlMessage{
-		HopLimit: 1,
-		Src: srcIP,
+		HopLimit: hopLimit,
+		Src:      srcIP,
 	}
 
-	return packet, cm, fmt.Errorf("IPv6 ")
+	return packet, cm, nil
 }
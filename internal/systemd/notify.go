@@ -0,0 +1,72 @@
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+func Status(s string) string {
+	return "STATUS=" + s
+}
+
+// Notify sends the given state assignments to the service manager via $NOTIFY_SOCKET. If the
+// process was not started by systemd with notification support, it does nothing and returns false.
+func Notify(state ...string) (bool, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return false, nil
+	}
+	// Abstract namespace socket
+	if strings.HasPrefix(path, "@") {
+		path = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("connecting to notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(strings.Join(state, "\n")))
+	if err != nil {
+		return false, fmt.Errorf("sending notification: %w", err)
+	}
+
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog timeout configured for this process (WatchdogSec= in the
+// unit file), or 0 if the watchdog is not enabled.
+func WatchdogInterval() (time.Duration, error) {
+	s := os.Getenv("WATCHDOG_USEC")
+	if s == "" {
+		return 0, nil
+	}
+
+	if p := os.Getenv("WATCHDOG_PID"); p != "" {
+		pid, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("invalid WATCHDOG_PID: %q", p)
+		}
+		if pid != os.Getpid() {
+			return 0, nil
+		}
+	}
+
+	usec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC: %q", s)
+	}
+
+	return time.Duration(usec) * time.Microsecond, nil
+}
@@ -0,0 +1,35 @@
+package wol
+
+import (
+	"bytes"
+	"errors"
+	"net"
+)
+
+var errNotMagicPacket = errors.New("not a magic packet")
+
+// ParseMagicPacket returns the hardware address a Wake-on-LAN magic packet is addressed to. A
+// magic packet is six 0xff bytes followed by sixteen copies of the target's MAC address,
+// optionally followed by a four or six byte SecureOn password.
+func ParseMagicPacket(data []byte) (net.HardwareAddr, error) {
+	const macLen = 6
+	const headerLen = 6
+	const packetLen = headerLen + 16*macLen
+
+	if len(data) != packetLen && len(data) != packetLen+4 && len(data) != packetLen+6 {
+		return nil, errNotMagicPacket
+	}
+	if !bytes.Equal(data[:headerLen], bytes.Repeat([]byte{0xff}, headerLen)) {
+		return nil, errNotMagicPacket
+	}
+
+	mac := data[headerLen : headerLen+macLen]
+	for i := 1; i < 16; i++ {
+		off := headerLen + i*macLen
+		if !bytes.Equal(data[off:off+macLen], mac) {
+			return nil, errNotMagicPacket
+		}
+	}
+
+	return net.HardwareAddr(bytes.Clone(mac)), nil
+}
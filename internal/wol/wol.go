@@ -0,0 +1,155 @@
+package wol
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+// Protocol returns a protocol that relays Wake-on-LAN magic packets broadcast to UDP ports 7 and
+// 9.
+func Protocol(policy *Policy) ssdp.Protocol {
+	p := ssdp.Broadcast("wol", 7, 9)
+	p.Policy = policy
+	return p
+}
+
+// Policy relays magic packets received on a source interface to a set of target interfaces,
+// subject to an allow list of MAC addresses and a rate limit. Inspect must only be called from
+// one goroutine at a time.
+type Policy struct {
+	source     string
+	targets    []string
+	interfaces []string
+	macs       []net.HardwareAddr
+	window     time.Duration
+	perMAC     int
+
+	windows map[string]*macWindow
+	now     func() time.Time
+}
+
+// macWindow counts the magic packets relayed for one hardware address in the window that began
+// when the first of them was received.
+type macWindow struct {
+	start time.Time
+	count int
+}
+
+type Option func(p *Policy) error
+
+// NewPolicy creates a policy that relays magic packets received on the source interface to the
+// target interfaces.
+func NewPolicy(source string, targets []string, opts ...Option) (*Policy, error) {
+	if source == "" {
+		return nil, fmt.Errorf("configuring Wake-on-LAN policy: no source interface")
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("configuring Wake-on-LAN policy: no target interfaces")
+	}
+
+	p := &Policy{
+		source:  source,
+		targets: targets,
+		window:  10 * time.Second,
+		perMAC:  5,
+		windows: make(map[string]*macWindow),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, fmt.Errorf("configuring Wake-on-LAN policy: %w", err)
+		}
+	}
+	if p.interfaces != nil {
+		for _, name := range append([]string{source}, targets...) {
+			if !slices.Contains(p.interfaces, name) {
+				return nil, fmt.Errorf("configuring Wake-on-LAN policy: %s is not a relay interface", name)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// WithMACs relays only magic packets addressed to one of the given hardware addresses. Without
+// this option, packets for any address are relayed.
+func WithMACs(macs ...string) Option {
+	return func(p *Policy) error {
+		for _, s := range macs {
+			mac, err := net.ParseMAC(s)
+			if err != nil {
+				return fmt.Errorf("invalid hardware address: %q", s)
+			}
+			p.macs = append(p.macs, mac)
+		}
+		return nil
+	}
+}
+
+// WithInterfaces requires the source and target interfaces to be among the named interfaces,
+// which should be those the relay listens and sends on.
+func WithInterfaces(names ...string) Option {
+	return func(p *Policy) error {
+		p.interfaces = append([]string{}, names...)
+		return nil
+	}
+}
+
+// WithRateLimit limits how many magic packets for each MAC address are relayed per window.
+func WithRateLimit(window time.Duration, perMAC int) Option {
+	return func(p *Policy) error {
+		if window <= 0 || perMAC <= 0 {
+			return fmt.Errorf("invalid rate limit: %d per %s", perMAC, window)
+		}
+		p.window = window
+		p.perMAC = perMAC
+		return nil
+	}
+}
+
+func (p *Policy) Inspect(m ssdp.Message) ssdp.Verdict {
+	if m.IfName != p.source {
+		return ssdp.Verdict{Reason: "not received on source interface", Level: slog.LevelDebug}
+	}
+
+	mac, err := ParseMagicPacket(m.Data)
+	if err != nil {
+		return ssdp.Verdict{Reason: err.Error(), Level: slog.LevelDebug}
+	}
+	v := ssdp.Verdict{Attrs: []any{"mac", mac.String()}}
+
+	if len(p.macs) > 0 && !slices.ContainsFunc(p.macs, func(a net.HardwareAddr) bool {
+		return slices.Equal(a, mac)
+	}) {
+		v.Reason = "hardware address not allowed"
+		v.Level = slog.LevelWarn
+		return v
+	}
+
+	now := p.now()
+	for k, w := range p.windows {
+		if now.Sub(w.start) >= p.window {
+			delete(p.windows, k)
+		}
+	}
+	w, found := p.windows[mac.String()]
+	if !found {
+		w = &macWindow{start: now}
+		p.windows[mac.String()] = w
+	}
+	if w.count >= p.perMAC {
+		v.Reason = fmt.Sprintf("more than %d packets for hardware address in %s", p.perMAC, p.window)
+		v.Level = slog.LevelWarn
+		return v
+	}
+	w.count++
+
+	v.Relay = true
+	v.Targets = p.targets
+	return v
+}
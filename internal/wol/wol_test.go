@@ -0,0 +1,120 @@
+package wol
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+func TestParseMagicPacket(t *testing.T) {
+	mac, err := ParseMagicPacket(magicPacket("00:11:22:33:44:55"))
+	assert.Nil(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", mac.String())
+
+	mac, err = ParseMagicPacket(append(magicPacket("00:11:22:33:44:55"), 1, 2, 3, 4))
+	assert.Nil(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", mac.String())
+}
+
+func TestParseMagicPacket_Invalid(t *testing.T) {
+	bad := magicPacket("00:11:22:33:44:55")
+	bad[50] ^= 0xff
+
+	for _, data := range [][]byte{
+		nil,
+		magicPacket("00:11:22:33:44:55")[:101],
+		append(magicPacket("00:11:22:33:44:55"), 1),
+		append([]byte{0}, magicPacket("00:11:22:33:44:55")[1:]...),
+		bad,
+	} {
+		_, err := ParseMagicPacket(data)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p, _ := NewPolicy("vlan10", []string{"vlan30"}, WithMACs("00:11:22:33:44:55"))
+
+	v := p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:55")))
+	assert.True(t, v.Relay)
+	assert.Equal(t, []string{"vlan30"}, v.Targets)
+
+	v = p.Inspect(message("vlan30", magicPacket("00:11:22:33:44:55")))
+	assert.False(t, v.Relay)
+
+	v = p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:66")))
+	assert.False(t, v.Relay)
+	assert.Equal(t, "hardware address not allowed", v.Reason)
+
+	v = p.Inspect(message("vlan10", []byte("hello")))
+	assert.False(t, v.Relay)
+}
+
+func TestPolicy_RateLimit(t *testing.T) {
+	p, _ := NewPolicy("vlan10", []string{"vlan30"}, WithRateLimit(time.Second, 2))
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	assert.True(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:55"))).Relay)
+	assert.True(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:55"))).Relay)
+	assert.False(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:55"))).Relay)
+	assert.True(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:66"))).Relay)
+
+	now = now.Add(time.Second)
+	assert.True(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:55"))).Relay)
+}
+
+func TestPolicy_RateLimitWindowPerMAC(t *testing.T) {
+	p, _ := NewPolicy("vlan10", []string{"vlan30"}, WithRateLimit(time.Second, 1))
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	assert.True(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:55"))).Relay)
+	now = now.Add(600 * time.Millisecond)
+	assert.True(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:66"))).Relay)
+
+	// The first address's window has ended, but the second's has not.
+	now = now.Add(600 * time.Millisecond)
+	assert.True(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:55"))).Relay)
+	assert.False(t, p.Inspect(message("vlan10", magicPacket("00:11:22:33:44:66"))).Relay)
+}
+
+func TestNewPolicy_WithInterfaces(t *testing.T) {
+	_, err := NewPolicy("vlan10", []string{"vlan30"}, WithInterfaces("vlan10", "vlan20", "vlan30"))
+	assert.Nil(t, err)
+
+	_, err = NewPolicy("vlan10", []string{"vlan30"}, WithInterfaces("vlan20", "vlan30"))
+	assert.EqualError(t, err, "configuring Wake-on-LAN policy: vlan10 is not a relay interface")
+
+	_, err = NewPolicy("vlan10", []string{"vlan20", "vlan40"}, WithInterfaces("vlan10", "vlan20"))
+	assert.EqualError(t, err, "configuring Wake-on-LAN policy: vlan40 is not a relay interface")
+}
+
+func TestNewPolicy_Invalid(t *testing.T) {
+	_, err := NewPolicy("", []string{"vlan30"})
+	assert.NotNil(t, err)
+	_, err = NewPolicy("vlan10", nil)
+	assert.NotNil(t, err)
+	_, err = NewPolicy("vlan10", []string{"vlan30"}, WithMACs("not-a-mac"))
+	assert.NotNil(t, err)
+}
+
+func magicPacket(mac string) []byte {
+	hw, _ := net.ParseMAC(mac)
+	return append(bytes.Repeat([]byte{0xff}, 6), bytes.Repeat(hw, 16)...)
+}
+
+func message(ifName string, data []byte) ssdp.Message {
+	return ssdp.Message{
+		Protocol: "wol",
+		Network:  "udp4",
+		IfName:   ifName,
+		SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.1.20"), Port: 40000},
+		Data:     data,
+	}
+}
@@ -0,0 +1,100 @@
+package wsd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// envelope holds the parts of a WS-Discovery SOAP envelope needed to decide whether to relay it.
+type envelope struct {
+	// Action is the last segment of the WS-Addressing action URI, e.g. "Probe".
+	Action    string
+	MessageID string
+	// Types are the QNames listed in the message body, as they appear in the message.
+	Types  []string
+	Scopes []string
+
+	qualifiedTypes []xml.Name
+}
+
+// parseEnvelope extracts the action, message ID, types and scopes from a SOAP envelope. Namespace
+// declarations are treated as global to the document, which is sufficient for the small, flat
+// messages WS-Discovery uses.
+func parseEnvelope(data []byte) (envelope, error) {
+	var env envelope
+	namespaces := map[string]string{"xml": "http://www.w3.org/XML/1998/namespace"}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var path []string
+	var text strings.Builder
+	for {
+		tok, err := d.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return envelope{}, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" {
+					namespaces[a.Name.Local] = a.Value
+				} else if a.Name.Space == "" && a.Name.Local == "xmlns" {
+					namespaces[""] = a.Value
+				}
+			}
+			path = append(path, t.Name.Local)
+			text.Reset()
+
+		case xml.CharData:
+			text.Write(t)
+
+		case xml.EndElement:
+			if len(path) == 0 {
+				return envelope{}, errors.New("unbalanced elements")
+			}
+			env.setField(path, strings.TrimSpace(text.String()))
+			path = path[:len(path)-1]
+			text.Reset()
+		}
+	}
+
+	if len(path) != 0 {
+		return envelope{}, errors.New("unexpected end of document")
+	}
+	if env.Action == "" {
+		return envelope{}, errors.New("missing action")
+	}
+
+	for _, t := range env.Types {
+		prefix, local, found := strings.Cut(t, ":")
+		if !found {
+			prefix, local = "", t
+		}
+		env.qualifiedTypes = append(env.qualifiedTypes, xml.Name{Space: namespaces[prefix], Local: local})
+	}
+
+	return env, nil
+}
+
+// setField records the text of the element at path, if it is one we care about.
+func (env *envelope) setField(path []string, text string) {
+	if len(path) < 3 || path[0] != "Envelope" {
+		return
+	}
+
+	switch {
+	case path[1] == "Header" && len(path) == 3 && path[2] == "Action":
+		env.Action = text[strings.LastIndex(text, "/")+1:]
+	case path[1] == "Header" && len(path) == 3 && path[2] == "MessageID":
+		env.MessageID = text
+	case path[1] == "Body" && len(path) == 4 && path[3] == "Types":
+		env.Types = append(env.Types, strings.Fields(text)...)
+	case path[1] == "Body" && len(path) == 4 && path[3] == "Scopes":
+		env.Scopes = append(env.Scopes, strings.Fields(text)...)
+	}
+}
@@ -0,0 +1,175 @@
+package wsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+var (
+	ipv4UDPAddr = &net.UDPAddr{IP: net.ParseIP("239.255.255.250"), Port: 3702}
+	ipv6UDPAddr = &net.UDPAddr{IP: net.ParseIP("ff02::c"), Port: 3702}
+)
+
+func Protocol(policy *Policy) ssdp.Protocol {
+	return ssdp.Protocol{
+		Name: "wsd",
+		Groups: []ssdp.Group{
+			{Addr: ipv4UDPAddr, HopLimit: 1},
+			{Addr: ipv6UDPAddr, HopLimit: 1},
+		},
+		Policy: policy,
+	}
+}
+
+// Policy relays multicast WS-Discovery messages (Probe, Resolve, Hello and Bye), dropping repeated
+// copies of a message and, optionally, messages that do not match allowed types or scopes. Inspect
+// must only be called from one goroutine at a time.
+type Policy struct {
+	types        []string
+	scopes       []string
+	dedupeWindow time.Duration
+	seen         map[string]time.Time
+	now          func() time.Time
+}
+
+type Option func(p *Policy) error
+
+func NewPolicy(opts ...Option) (*Policy, error) {
+	p := &Policy{
+		dedupeWindow: 10 * time.Second,
+		seen:         make(map[string]time.Time),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, fmt.Errorf("configuring WS-Discovery policy: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// WithTypes relays only messages that mention one of the given types. A type is either a local
+// name (e.g. "PrintDeviceType") or a namespace and local name in the form "{namespace}local".
+// Probes that do not specify any types are always relayed.
+func WithTypes(types ...string) Option {
+	return func(p *Policy) error {
+		p.types = append(p.types, types...)
+		return nil
+	}
+}
+
+// WithScopes relays only messages that mention a scope beginning with one of the given prefixes.
+// Messages that do not specify any scopes are always relayed.
+func WithScopes(scopes ...string) Option {
+	return func(p *Policy) error {
+		p.scopes = append(p.scopes, scopes...)
+		return nil
+	}
+}
+
+// WithDedupeWindow sets how long a message ID is remembered, so that retransmissions of the same
+// message are not relayed again.
+func WithDedupeWindow(d time.Duration) Option {
+	return func(p *Policy) error {
+		if d <= 0 {
+			return fmt.Errorf("invalid dedupe window: %s", d)
+		}
+		p.dedupeWindow = d
+		return nil
+	}
+}
+
+func (p *Policy) Inspect(m ssdp.Message) ssdp.Verdict {
+	env, err := parseEnvelope(m.Data)
+	if err != nil {
+		return ssdp.Verdict{Reason: fmt.Sprintf("error parsing SOAP envelope: %s", err.Error()), Level: slog.LevelWarn}
+	}
+
+	v := ssdp.Verdict{Attrs: []any{"action", env.Action, "message_id", env.MessageID}}
+	if len(env.Types) > 0 {
+		v.Attrs = append(v.Attrs, "types", strings.Join(env.Types, " "))
+	}
+
+	switch env.Action {
+	case "Probe", "Resolve", "Hello", "Bye":
+	default:
+		v.Reason = "unsupported action"
+		v.Level = slog.LevelDebug
+		return v
+	}
+
+	if env.MessageID == "" {
+		v.Reason = "missing message ID"
+		v.Level = slog.LevelWarn
+		return v
+	}
+	if p.isDuplicate(env.MessageID) {
+		v.Reason = "duplicate message"
+		v.Level = slog.LevelDebug
+		return v
+	}
+
+	if !p.typesAllowed(env.qualifiedTypes) {
+		v.Reason = "no allowed types"
+		v.Level = slog.LevelDebug
+		return v
+	}
+	if !p.scopesAllowed(env.Scopes) {
+		v.Reason = "no allowed scopes"
+		v.Level = slog.LevelDebug
+		return v
+	}
+
+	v.Relay = true
+	return v
+}
+
+// isDuplicate records id and reports whether it was already seen within the dedupe window.
+func (p *Policy) isDuplicate(id string) bool {
+	now := p.now()
+	for seenID, t := range p.seen {
+		if now.Sub(t) >= p.dedupeWindow {
+			delete(p.seen, seenID)
+		}
+	}
+
+	if _, found := p.seen[id]; found {
+		return true
+	}
+	p.seen[id] = now
+	return false
+}
+
+func (p *Policy) typesAllowed(types []xml.Name) bool {
+	if len(p.types) == 0 || len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		for _, allowed := range p.types {
+			if allowed == t.Local || allowed == "{"+t.Space+"}"+t.Local {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Policy) scopesAllowed(scopes []string) bool {
+	if len(p.scopes) == 0 || len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		for _, allowed := range p.scopes {
+			if strings.HasPrefix(s, allowed) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package wsd
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/edutko/go-forward-ssdp/internal/ssdp"
+)
+
+func TestPolicy_Probe(t *testing.T) {
+	p, _ := NewPolicy()
+
+	v := p.Inspect(message(probe("uuid:1", "wsdp:Device wprt:PrintDeviceType", "")))
+	assert.True(t, v.Relay)
+	assert.Equal(t, []any{"action", "Probe", "message_id", "urn:uuid:1", "types", "wsdp:Device wprt:PrintDeviceType"}, v.Attrs)
+}
+
+func TestPolicy_Duplicate(t *testing.T) {
+	p, _ := NewPolicy(WithDedupeWindow(time.Second))
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	assert.True(t, p.Inspect(message(probe("uuid:1", "", ""))).Relay)
+	v := p.Inspect(message(probe("uuid:1", "", "")))
+	assert.False(t, v.Relay)
+	assert.Equal(t, "duplicate message", v.Reason)
+	assert.True(t, p.Inspect(message(probe("uuid:2", "", ""))).Relay)
+
+	now = now.Add(time.Second)
+	assert.True(t, p.Inspect(message(probe("uuid:1", "", ""))).Relay)
+}
+
+func TestPolicy_Types(t *testing.T) {
+	p, _ := NewPolicy(WithTypes("ScanDeviceType", "{http://schemas.microsoft.com/windows/2006/08/wdp/print}PrintDeviceType"))
+
+	assert.True(t, p.Inspect(message(probe("uuid:1", "wprt:PrintDeviceType", ""))).Relay)
+	assert.True(t, p.Inspect(message(probe("uuid:2", "wscn:ScanDeviceType", ""))).Relay)
+	assert.False(t, p.Inspect(message(probe("uuid:3", "wsdp:Device", ""))).Relay)
+	assert.False(t, p.Inspect(message(probe("uuid:4", "other:PrintDeviceType", ""))).Relay)
+	assert.True(t, p.Inspect(message(probe("uuid:5", "", ""))).Relay)
+}
+
+func TestPolicy_Scopes(t *testing.T) {
+	p, _ := NewPolicy(WithScopes("ldap:///ou=office"))
+
+	assert.True(t, p.Inspect(message(probe("uuid:1", "", "ldap:///ou=office/printers"))).Relay)
+	assert.False(t, p.Inspect(message(probe("uuid:2", "", "ldap:///ou=lab"))).Relay)
+	assert.True(t, p.Inspect(message(probe("uuid:3", "", ""))).Relay)
+}
+
+func TestPolicy_UnsupportedAction(t *testing.T) {
+	p, _ := NewPolicy()
+
+	v := p.Inspect(message(envelopeFor("ProbeMatches", "uuid:1", "")))
+	assert.False(t, v.Relay)
+	assert.Equal(t, "unsupported action", v.Reason)
+}
+
+func TestPolicy_Malformed(t *testing.T) {
+	p, _ := NewPolicy()
+
+	for _, data := range []string{"", "<soap:Envelope>", "NOTIFY * HTTP/1.1\r\n\r\n"} {
+		v := p.Inspect(message(data))
+		assert.False(t, v.Relay, data)
+	}
+}
+
+func probe(id, types, scopes string) string {
+	body := "<wsd:Probe>"
+	if types != "" {
+		body += "<wsd:Types>" + types + "</wsd:Types>"
+	}
+	if scopes != "" {
+		body += "<wsd:Scopes>" + scopes + "</wsd:Scopes>"
+	}
+	body += "</wsd:Probe>"
+	return envelopeFor("Probe", id, body)
+}
+
+func envelopeFor(action, id, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope"
+    xmlns:wsa="http://schemas.xmlsoap.org/ws/2004/08/addressing"
+    xmlns:wsd="http://schemas.xmlsoap.org/ws/2005/04/discovery"
+    xmlns:wsdp="http://schemas.xmlsoap.org/ws/2006/02/devprof"
+    xmlns:wprt="http://schemas.microsoft.com/windows/2006/08/wdp/print"
+    xmlns:wscn="http://schemas.microsoft.com/windows/2006/08/wdp/scan"
+    xmlns:other="urn:example">
+  <soap:Header>
+    <wsa:To>urn:schemas-xmlsoap-org:ws:2005:04:discovery</wsa:To>
+    <wsa:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/%s</wsa:Action>
+    <wsa:MessageID>urn:%s</wsa:MessageID>
+  </soap:Header>
+  <soap:Body>%s</soap:Body>
+</soap:Envelope>`, action, id, body)
+}
+
+func message(data string) ssdp.Message {
+	return ssdp.Message{
+		Protocol: "wsd",
+		Network:  "udp4",
+		IfName:   "vlan10",
+		SourceIP: &net.UDPAddr{IP: net.ParseIP("192.168.1.20"), Port: 3702},
+		Data:     []byte(data),
+	}
+}